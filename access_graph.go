@@ -0,0 +1,372 @@
+package accountslib
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// maxConcurrentFetches caps the number of requests a concurrentFetcher has in flight.
+const maxConcurrentFetches = 8
+
+// Account relations recorded in an access graph.
+const (
+	AccessRelationOwner  = "owner"
+	AccessRelationLinked = "linked"
+	AccessRelationMember = "member"
+)
+
+// AccountRef identifies an account of any kind.
+type AccountRef struct {
+	AccountType string    `json:"account_type"`
+	AccountID   uuid.UUID `json:"account_id"`
+}
+
+// AccountAccess describes how a user reaches one account and what they can do in it.
+type AccountAccess struct {
+	AccountType string       `json:"account_type"`
+	AccountID   uuid.UUID    `json:"account_id"`
+	Relations   []string     `json:"relations"`
+	Roles       []Role       `json:"roles"`
+	Permissions []Permission `json:"permissions"`
+}
+
+// UserAccessGraph is everything a user can touch, gathered from the account, link,
+// membership, role and permission endpoints.
+type UserAccessGraph struct {
+	UserID         uuid.UUID           `json:"user_id"`
+	OwnedAccounts  []AccountRef        `json:"owned_accounts"`
+	LinkedAccounts []AccountLink       `json:"linked_accounts"`
+	Memberships    []AccountMembership `json:"memberships"`
+	Access         []AccountAccess     `json:"access"`
+	// RolePermissions maps each role in Access to the permissions it grants.
+	RolePermissions map[uuid.UUID][]Permission `json:"role_permissions"`
+}
+
+// concurrentFetcher runs fetches concurrently with a bounded number in flight and keeps the first error.
+type concurrentFetcher struct {
+	ctx context.Context
+	wg  sync.WaitGroup
+	sem chan struct{}
+	mu  sync.Mutex
+	err error
+}
+
+func (f *concurrentFetcher) run(fetch func() error) {
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+
+		select {
+		case f.sem <- struct{}{}:
+		case <-f.ctx.Done():
+			f.fail(f.ctx.Err())
+			return
+		}
+		defer func() { <-f.sem }()
+
+		if f.ctx.Err() != nil {
+			f.fail(f.ctx.Err())
+			return
+		}
+		if err := fetch(); err != nil {
+			f.fail(err)
+		}
+	}()
+}
+
+func (f *concurrentFetcher) fail(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err == nil {
+		f.err = err
+	}
+}
+
+func (f *concurrentFetcher) wait() error {
+	f.wg.Wait()
+	return f.err
+}
+
+// GetUserAccessGraph collects the accounts a user owns, is linked to and is a member of,
+// together with the roles and permissions the user holds in each of them.
+func (c *Client) GetUserAccessGraph(ctx context.Context, userID uuid.UUID) (*UserAccessGraph, error) {
+	if userID == uuid.Nil {
+		return nil, fmt.Errorf("user ID is required")
+	}
+
+	graph := &UserAccessGraph{UserID: userID}
+	fetcher := &concurrentFetcher{ctx: ctx, sem: make(chan struct{}, maxConcurrentFetches)}
+
+	// Fetch owned accounts, links and memberships concurrently
+	var mu sync.Mutex
+	addOwned := func(accountType string, ids []uuid.UUID) {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, id := range ids {
+			graph.OwnedAccounts = append(graph.OwnedAccounts, AccountRef{AccountType: accountType, AccountID: id})
+		}
+	}
+
+	fetcher.run(func() error {
+		agencies, err := c.GetAgencyAccountsByUserID(userID)
+		if err != nil {
+			return fmt.Errorf("fetching agency accounts: %w", err)
+		}
+		ids := make([]uuid.UUID, len(agencies))
+		for i, agency := range agencies {
+			ids[i] = agency.ID
+		}
+		addOwned("agency", ids)
+		return nil
+	})
+	fetcher.run(func() error {
+		businesses, err := c.GetBusinessAccountsByUserID(userID)
+		if err != nil {
+			return fmt.Errorf("fetching business accounts: %w", err)
+		}
+		ids := make([]uuid.UUID, len(businesses))
+		for i, business := range businesses {
+			ids[i] = business.ID
+		}
+		addOwned("business", ids)
+		return nil
+	})
+	fetcher.run(func() error {
+		celebrities, err := c.GetCelebrityAccountsByUserID(userID)
+		if err != nil {
+			return fmt.Errorf("fetching celebrity accounts: %w", err)
+		}
+		ids := make([]uuid.UUID, len(celebrities))
+		for i, celebrity := range celebrities {
+			ids[i] = celebrity.ID
+		}
+		addOwned("celebrity", ids)
+		return nil
+	})
+	fetcher.run(func() error {
+		enterprises, err := c.GetEnterpriseAccountsByUserID(userID)
+		if err != nil {
+			return fmt.Errorf("fetching enterprise accounts: %w", err)
+		}
+		ids := make([]uuid.UUID, len(enterprises))
+		for i, enterprise := range enterprises {
+			ids[i] = enterprise.ID
+		}
+		addOwned("enterprise", ids)
+		return nil
+	})
+	fetcher.run(func() error {
+		governments, err := c.GetGovernmentAccountsByUserID(userID)
+		if err != nil {
+			return fmt.Errorf("fetching government accounts: %w", err)
+		}
+		ids := make([]uuid.UUID, len(governments))
+		for i, government := range governments {
+			ids[i] = government.ID
+		}
+		addOwned("government", ids)
+		return nil
+	})
+	fetcher.run(func() error {
+		links, err := c.GetAccountLinksByUserID(userID)
+		if err != nil {
+			return fmt.Errorf("fetching account links: %w", err)
+		}
+		mu.Lock()
+		graph.LinkedAccounts = links
+		mu.Unlock()
+		return nil
+	})
+	fetcher.run(func() error {
+		memberships, err := c.GetAccountMembershipsByUserID(userID)
+		if err != nil {
+			return fmt.Errorf("fetching account memberships: %w", err)
+		}
+		mu.Lock()
+		graph.Memberships = memberships
+		mu.Unlock()
+		return nil
+	})
+
+	if err := fetcher.wait(); err != nil {
+		return nil, err
+	}
+
+	// Merge every way the user reaches an account into one entry per account
+	access := make(map[uuid.UUID]*AccountAccess)
+	var order []uuid.UUID
+	addAccess := func(accountType string, accountID uuid.UUID, relation string) {
+		entry, ok := access[accountID]
+		if !ok {
+			entry = &AccountAccess{AccountType: accountType, AccountID: accountID}
+			access[accountID] = entry
+			order = append(order, accountID)
+		}
+		for _, existing := range entry.Relations {
+			if existing == relation {
+				return
+			}
+		}
+		entry.Relations = append(entry.Relations, relation)
+	}
+	for _, ref := range graph.OwnedAccounts {
+		addAccess(ref.AccountType, ref.AccountID, AccessRelationOwner)
+	}
+	for _, link := range graph.LinkedAccounts {
+		addAccess(link.AccountType, link.AccountID, AccessRelationLinked)
+	}
+	for _, membership := range graph.Memberships {
+		addAccess(membership.AccountType, membership.AccountID, AccessRelationMember)
+	}
+
+	// Fetch the user's roles in each account concurrently
+	fetcher = &concurrentFetcher{ctx: ctx, sem: make(chan struct{}, maxConcurrentFetches)}
+	for _, accountID := range order {
+		entry := access[accountID]
+		fetcher.run(func() error {
+			roles, err := c.GetRolesForUserInAccount(userID, entry.AccountID)
+			if err != nil {
+				return fmt.Errorf("fetching roles in %s account %s: %w", entry.AccountType, entry.AccountID, err)
+			}
+			entry.Roles = roles
+			return nil
+		})
+	}
+	if err := fetcher.wait(); err != nil {
+		return nil, err
+	}
+
+	// Fetch the permissions of each distinct role once
+	graph.RolePermissions = make(map[uuid.UUID][]Permission)
+	var roleIDs []uuid.UUID
+	for _, accountID := range order {
+		for _, role := range access[accountID].Roles {
+			if _, ok := graph.RolePermissions[role.ID]; !ok {
+				graph.RolePermissions[role.ID] = nil
+				roleIDs = append(roleIDs, role.ID)
+			}
+		}
+	}
+
+	fetcher = &concurrentFetcher{ctx: ctx, sem: make(chan struct{}, maxConcurrentFetches)}
+	for _, roleID := range roleIDs {
+		fetcher.run(func() error {
+			permissions, err := c.GetPermissionsByRoleID(&GetPermissionsByRoleIDInput{RoleID: roleID})
+			if err != nil {
+				return fmt.Errorf("fetching permissions of role %s: %w", roleID, err)
+			}
+			mu.Lock()
+			graph.RolePermissions[roleID] = permissions
+			mu.Unlock()
+			return nil
+		})
+	}
+	if err := fetcher.wait(); err != nil {
+		return nil, err
+	}
+
+	// Resolve effective permissions per account as the union of its roles' permissions
+	for _, accountID := range order {
+		entry := access[accountID]
+		seen := make(map[uuid.UUID]bool)
+		for _, role := range entry.Roles {
+			for _, permission := range graph.RolePermissions[role.ID] {
+				if seen[permission.ID] {
+					continue
+				}
+				seen[permission.ID] = true
+				entry.Permissions = append(entry.Permissions, permission)
+			}
+		}
+		sort.Slice(entry.Permissions, func(i, j int) bool { return entry.Permissions[i].Name < entry.Permissions[j].Name })
+		graph.Access = append(graph.Access, *entry)
+	}
+
+	sort.Slice(graph.OwnedAccounts, func(i, j int) bool {
+		if graph.OwnedAccounts[i].AccountType != graph.OwnedAccounts[j].AccountType {
+			return graph.OwnedAccounts[i].AccountType < graph.OwnedAccounts[j].AccountType
+		}
+		return graph.OwnedAccounts[i].AccountID.String() < graph.OwnedAccounts[j].AccountID.String()
+	})
+	sort.Slice(graph.Access, func(i, j int) bool {
+		if graph.Access[i].AccountType != graph.Access[j].AccountType {
+			return graph.Access[i].AccountType < graph.Access[j].AccountType
+		}
+		return graph.Access[i].AccountID.String() < graph.Access[j].AccountID.String()
+	})
+
+	return graph, nil
+}
+
+// WriteJSON writes the graph to w as indented JSON.
+func (g *UserAccessGraph) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(g)
+}
+
+// WriteDOT writes the graph to w in Graphviz DOT format. Edges run from the user to each
+// account, from each account to the roles the user holds there, and from each role to its permissions.
+func (g *UserAccessGraph) WriteDOT(w io.Writer) error {
+	var b strings.Builder
+
+	b.WriteString("digraph access {\n")
+	b.WriteString("\trankdir=LR;\n")
+	fmt.Fprintf(&b, "\t%s [label=%s, shape=circle];\n", dotString("user:"+g.UserID.String()), dotString("user", g.UserID.String()))
+
+	roles := make(map[uuid.UUID]Role)
+	for _, entry := range g.Access {
+		accountNode := entry.AccountType + ":" + entry.AccountID.String()
+		fmt.Fprintf(&b, "\t%s [label=%s, shape=box];\n", dotString(accountNode), dotString(entry.AccountType, entry.AccountID.String()))
+		fmt.Fprintf(&b, "\t%s -> %s [label=%s];\n", dotString("user:"+g.UserID.String()), dotString(accountNode), dotString(strings.Join(entry.Relations, ", ")))
+
+		for _, role := range entry.Roles {
+			roles[role.ID] = role
+			fmt.Fprintf(&b, "\t%s -> %s;\n", dotString(accountNode), dotString("role:"+role.ID.String()))
+		}
+	}
+
+	// Roles are shared between accounts, so their nodes and permission edges are written once
+	roleIDs := make([]uuid.UUID, 0, len(roles))
+	for id := range roles {
+		roleIDs = append(roleIDs, id)
+	}
+	sort.Slice(roleIDs, func(i, j int) bool { return roleIDs[i].String() < roleIDs[j].String() })
+
+	permissions := make(map[uuid.UUID]bool)
+	for _, id := range roleIDs {
+		roleNode := "role:" + id.String()
+		fmt.Fprintf(&b, "\t%s [label=%s, shape=ellipse];\n", dotString(roleNode), dotString(roles[id].Name))
+		for _, permission := range g.RolePermissions[id] {
+			permissionNode := "permission:" + permission.ID.String()
+			if !permissions[permission.ID] {
+				permissions[permission.ID] = true
+				fmt.Fprintf(&b, "\t%s [label=%s, shape=note];\n", dotString(permissionNode), dotString(permission.Name))
+			}
+			fmt.Fprintf(&b, "\t%s -> %s;\n", dotString(roleNode), dotString(permissionNode))
+		}
+	}
+
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// dotString quotes lines as a DOT string, one label line each. Only backslashes and double quotes
+// are escaped, as DOT does not understand Go escapes such as those written by %q.
+func dotString(lines ...string) string {
+	escaper := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	escaped := make([]string, len(lines))
+	for i, line := range lines {
+		escaped[i] = escaper.Replace(line)
+	}
+	return `"` + strings.Join(escaped, `\n`) + `"`
+}
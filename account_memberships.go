@@ -2,6 +2,7 @@ package accountslib
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -33,6 +34,17 @@ type UpdateAccountMembershipEvent struct {
 
 // CreateAccountMembership sends a POST request to create a new account membership.
 func (c *Client) CreateAccountMembership(accountMembership *AccountMembership) (*AccountMembership, error) {
+	return c.createAccountMembership(context.Background(), accountMembership)
+}
+
+// createAccountMembership is CreateAccountMembership bound to ctx.
+func (c *Client) createAccountMembership(ctx context.Context, accountMembership *AccountMembership) (*AccountMembership, error) {
+	// Check the role may be given to the user
+	err := c.enforceCompanyDomainByRoleName(accountMembership.UserID, accountMembership.Role, accountMembership.AccountType, accountMembership.AccountID)
+	if err != nil {
+		return nil, err
+	}
+
 	// Convert the AccountMembership struct to JSON
 	accountMembershipJSON, err := json.Marshal(accountMembership)
 	if err != nil {
@@ -40,7 +52,7 @@ func (c *Client) CreateAccountMembership(accountMembership *AccountMembership) (
 	}
 
 	// Create a new request
-	req, err := http.NewRequest("POST", c.BaseURL+"/api/account-membership", bytes.NewBuffer(accountMembershipJSON))
+	req, err := http.NewRequestWithContext(ctx, "POST", c.BaseURL+"/api/account-membership", bytes.NewBuffer(accountMembershipJSON))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %v", err)
 	}
@@ -231,6 +243,26 @@ func (c *Client) GetAccountMembershipsByAccountType(accountType string) ([]Accou
 }
 
 func (c *Client) UpdateAccountMembership(accountMembershipID uuid.UUID, event UpdateAccountMembershipEvent) (AccountMembership, error) {
+	// Check a new role may be given to the member
+	if event.Role != "" && c.emailPolicy().enforcesCompanyDomains() {
+		membership, err := c.GetAccountMembershipByID(accountMembershipID)
+		if err != nil {
+			return AccountMembership{}, fmt.Errorf("failed to fetch account membership: %w", err)
+		}
+		if event.UserID != uuid.Nil {
+			membership.UserID = event.UserID
+		}
+		if event.AccountType != "" {
+			membership.AccountType = event.AccountType
+		}
+		if event.AccountID != uuid.Nil {
+			membership.AccountID = event.AccountID
+		}
+		if err := c.enforceCompanyDomainByRoleName(membership.UserID, event.Role, membership.AccountType, membership.AccountID); err != nil {
+			return AccountMembership{}, err
+		}
+	}
+
 	// Convert the event to JSON
 	jsonEvent, err := json.Marshal(event)
 	if err != nil {
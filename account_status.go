@@ -0,0 +1,326 @@
+package accountslib
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/google/uuid"
+)
+
+// AccountStatus represents the lifecycle status of an organization account.
+type AccountStatus string
+
+const (
+	AccountStatusActive              AccountStatus = "active"
+	AccountStatusSuspended           AccountStatus = "suspended"
+	AccountStatusPendingVerification AccountStatus = "pending_verification"
+	AccountStatusClosed              AccountStatus = "closed"
+)
+
+// ErrInvalidStatusTransition is returned when an account cannot move from its current status to the requested one.
+var ErrInvalidStatusTransition = errors.New("invalid account status transition")
+
+// organizationAccountTypes lists the account types that support the status lifecycle.
+var organizationAccountTypes = []interface{}{"agency", "business", "celebrity", "enterprise", "government"}
+
+// accountStatusTransitions lists the statuses each status may move to.
+// Closed is terminal.
+var accountStatusTransitions = map[AccountStatus][]AccountStatus{
+	AccountStatusPendingVerification: {AccountStatusActive, AccountStatusSuspended, AccountStatusClosed},
+	AccountStatusActive:              {AccountStatusSuspended, AccountStatusPendingVerification, AccountStatusClosed},
+	AccountStatusSuspended:           {AccountStatusActive, AccountStatusClosed},
+}
+
+// IsValid reports whether s is a known account status.
+func (s AccountStatus) IsValid() bool {
+	switch s {
+	case AccountStatusActive, AccountStatusSuspended, AccountStatusPendingVerification, AccountStatusClosed:
+		return true
+	}
+	return false
+}
+
+// CanTransitionTo reports whether an account in status s may be moved to next.
+func (s AccountStatus) CanTransitionTo(next AccountStatus) bool {
+	for _, allowed := range accountStatusTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// AccountStatusChange represents a single entry in an account's status history.
+type AccountStatusChange struct {
+	ID          uuid.UUID     `json:"id"`
+	AccountType string        `json:"account_type"`
+	AccountID   uuid.UUID     `json:"account_id"`
+	From        AccountStatus `json:"from"`
+	To          AccountStatus `json:"to"`
+	Reason      string        `json:"reason"`
+	ChangedBy   uuid.UUID     `json:"changed_by,omitempty"`
+	ChangedAt   time.Time     `json:"changed_at"`
+}
+
+// SetAccountStatusInput represents the input data for changing the status of an organization account.
+type SetAccountStatusInput struct {
+	AccountType string        `json:"account_type"`
+	AccountID   uuid.UUID     `json:"account_id"`
+	Status      AccountStatus `json:"status"`
+	Reason      string        `json:"reason"`
+}
+
+// SuspendAccountInput represents the input data for suspending an organization account.
+type SuspendAccountInput struct {
+	AccountType string    `json:"account_type"`
+	AccountID   uuid.UUID `json:"account_id"`
+	Reason      string    `json:"reason"`
+}
+
+// ReactivateAccountInput represents the input data for reactivating a suspended organization account.
+type ReactivateAccountInput struct {
+	AccountType string    `json:"account_type"`
+	AccountID   uuid.UUID `json:"account_id"`
+	Reason      string    `json:"reason"`
+}
+
+// Validate validates the SetAccountStatusInput fields.
+func (input *SetAccountStatusInput) Validate() error {
+	return validation.ValidateStruct(input,
+		validation.Field(&input.AccountType, validation.Required, validation.In(organizationAccountTypes...).Error("must be an organization account type")),
+		validation.Field(&input.AccountID, validation.Required, validation.NotIn(uuid.Nil).Error("Invalid AccountID")),
+		validation.Field(&input.Status, validation.Required, validation.By(func(value interface{}) error {
+			if !value.(AccountStatus).IsValid() {
+				return errors.New("unknown account status")
+			}
+			return nil
+		})),
+		validation.Field(&input.Reason, validation.Required, validation.Length(1, 1000)),
+	)
+}
+
+// SuspendAccount suspends an organization account. A reason is required and is kept in the status history.
+func (c *Client) SuspendAccount(ctx context.Context, input SuspendAccountInput) (*AccountStatusChange, error) {
+	return c.SetAccountStatus(ctx, SetAccountStatusInput{
+		AccountType: input.AccountType,
+		AccountID:   input.AccountID,
+		Status:      AccountStatusSuspended,
+		Reason:      input.Reason,
+	})
+}
+
+// ReactivateAccount moves a suspended organization account back to active. Accounts in any
+// other status fail with ErrInvalidStatusTransition; in particular an account pending
+// verification cannot be activated this way.
+func (c *Client) ReactivateAccount(ctx context.Context, input ReactivateAccountInput) (*AccountStatusChange, error) {
+	return c.setAccountStatus(ctx, SetAccountStatusInput{
+		AccountType: input.AccountType,
+		AccountID:   input.AccountID,
+		Status:      AccountStatusActive,
+		Reason:      input.Reason,
+	}, AccountStatusSuspended)
+}
+
+// SetAccountStatus moves an organization account to the given status and returns the recorded change.
+// Transitions that AccountStatus.CanTransitionTo does not allow fail with ErrInvalidStatusTransition.
+func (c *Client) SetAccountStatus(ctx context.Context, input SetAccountStatusInput) (*AccountStatusChange, error) {
+	return c.setAccountStatus(ctx, input)
+}
+
+// setAccountStatus is SetAccountStatus restricted to accounts currently in one of from, when from is not empty.
+func (c *Client) setAccountStatus(ctx context.Context, input SetAccountStatusInput, from ...AccountStatus) (*AccountStatusChange, error) {
+	// Validate the input
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Check the transition against the account's current status
+	current, err := c.accountStatus(input.AccountType, input.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	allowed := len(from) == 0
+	for _, status := range from {
+		if current == status {
+			allowed = true
+		}
+	}
+	if !allowed || !current.CanTransitionTo(input.Status) {
+		return nil, fmt.Errorf("%w: %s to %s", ErrInvalidStatusTransition, current, input.Status)
+	}
+
+	// Marshal the input
+	jsonPayload, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal input: %w", err)
+	}
+
+	// Create the URL
+	u, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse base URL: %w", err)
+	}
+	u.Path = path.Join(u.Path, "api", "accounts", input.AccountType, input.AccountID.String(), "status")
+
+	// Create a new HTTP request
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.String(), bytes.NewBuffer(jsonPayload))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create new request: %w", err)
+	}
+
+	// Set the appropriate headers
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	req.Header.Set("X-API-Key", c.ApiKey)
+
+	// Send the HTTP request
+	res, err := c.HttpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to send request: %w", err)
+	}
+	defer res.Body.Close()
+
+	// Check the status code
+	if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(res.Body)
+		return nil, fmt.Errorf("unexpected status code: got %v, body: %s", res.StatusCode, body)
+	}
+
+	// Decode the response body
+	var change AccountStatusChange
+	if err := json.NewDecoder(res.Body).Decode(&change); err != nil {
+		return nil, fmt.Errorf("unable to decode response body: %w", err)
+	}
+
+	return &change, nil
+}
+
+// GetAccountStatusHistory returns the status changes of an organization account, oldest first.
+func (c *Client) GetAccountStatusHistory(ctx context.Context, accountType string, accountID uuid.UUID) ([]AccountStatusChange, error) {
+	// Validate the input
+	if err := validation.Validate(accountType, validation.Required, validation.In(organizationAccountTypes...)); err != nil {
+		return nil, fmt.Errorf("invalid account type: %w", err)
+	}
+	if accountID == uuid.Nil {
+		return nil, errors.New("account ID is required")
+	}
+
+	// Create the URL
+	u, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse base URL: %w", err)
+	}
+	u.Path = path.Join(u.Path, "api", "accounts", accountType, accountID.String(), "status", "history")
+
+	// Create a new HTTP request
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create new request: %w", err)
+	}
+
+	// Set the appropriate headers
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	req.Header.Set("X-API-Key", c.ApiKey)
+
+	// Send the HTTP request
+	res, err := c.HttpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to send request: %w", err)
+	}
+	defer res.Body.Close()
+
+	// Check the status code
+	if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(res.Body)
+		return nil, fmt.Errorf("unexpected status code: got %v, body: %s", res.StatusCode, body)
+	}
+
+	// Decode the response body
+	var history []AccountStatusChange
+	if err := json.NewDecoder(res.Body).Decode(&history); err != nil {
+		return nil, fmt.Errorf("unable to decode response body: %w", err)
+	}
+
+	return history, nil
+}
+
+// accountStatus fetches the current status of an organization account. Accounts created
+// before the lifecycle existed have no status and are treated as active.
+func (c *Client) accountStatus(accountType string, accountID uuid.UUID) (AccountStatus, error) {
+	var status AccountStatus
+	switch accountType {
+	case "agency":
+		account, err := c.GetAgencyAccountByID(accountID)
+		if err != nil {
+			return "", fmt.Errorf("unable to fetch account: %w", err)
+		}
+		status = account.Status
+	case "business":
+		account, err := c.GetBusinessAccountByID(accountID)
+		if err != nil {
+			return "", fmt.Errorf("unable to fetch account: %w", err)
+		}
+		status = account.Status
+	case "celebrity":
+		account, err := c.GetCelebrityAccountByID(accountID)
+		if err != nil {
+			return "", fmt.Errorf("unable to fetch account: %w", err)
+		}
+		status = account.Status
+	case "enterprise":
+		account, err := c.GetEnterpriseAccountByID(accountID)
+		if err != nil {
+			return "", fmt.Errorf("unable to fetch account: %w", err)
+		}
+		status = account.Status
+	case "government":
+		account, err := c.GetGovernmentAccountByID(accountID)
+		if err != nil {
+			return "", fmt.Errorf("unable to fetch account: %w", err)
+		}
+		status = account.Status
+	default:
+		return "", fmt.Errorf("unknown organization account type %q", accountType)
+	}
+
+	if status == "" {
+		status = AccountStatusActive
+	}
+	return status, nil
+}
+
+// accountStatusQuery encodes a status filter for the list endpoints.
+func accountStatusQuery(statuses []AccountStatus) string {
+	query := url.Values{}
+	for _, status := range statuses {
+		query.Add("status", string(status))
+	}
+	return query.Encode()
+}
+
+// matchesAccountStatus reports whether status passes the filter. An empty filter matches everything.
+// Accounts created before the lifecycle existed have no status and are treated as active.
+func matchesAccountStatus(status AccountStatus, statuses []AccountStatus) bool {
+	if len(statuses) == 0 {
+		return true
+	}
+	if status == "" {
+		status = AccountStatusActive
+	}
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
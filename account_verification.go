@@ -0,0 +1,386 @@
+package accountslib
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// VerificationStatus represents the review state of a verification request.
+type VerificationStatus string
+
+const (
+	VerificationStatusPending  VerificationStatus = "pending"
+	VerificationStatusApproved VerificationStatus = "approved"
+	VerificationStatusRejected VerificationStatus = "rejected"
+)
+
+// maxEvidenceDocumentSize is the largest evidence document accepted for upload.
+const maxEvidenceDocumentSize = 10 << 20
+
+// VerificationRequirement describes one item of evidence an account kind must provide.
+type VerificationRequirement struct {
+	Key          string   `json:"key"`
+	Description  string   `json:"description"`
+	Required     bool     `json:"required"`
+	ContentTypes []string `json:"content_types"`
+}
+
+var evidenceContentTypes = []string{"application/pdf", "image/png", "image/jpeg"}
+
+// verificationChecklists holds the evidence required for each account kind that can be verified.
+var verificationChecklists = map[string][]VerificationRequirement{
+	"government": {
+		{Key: "authorization_letter", Description: "Letter on official letterhead authorizing the account", Required: true, ContentTypes: evidenceContentTypes},
+		{Key: "representative_id", Description: "Government issued ID of the account representative", Required: true, ContentTypes: evidenceContentTypes},
+		{Key: "domain_ownership", Description: "Proof of control over an official government domain", Required: false, ContentTypes: evidenceContentTypes},
+	},
+	"celebrity": {
+		{Key: "identity_document", Description: "Government issued ID of the public figure", Required: true, ContentTypes: evidenceContentTypes},
+		{Key: "public_presence", Description: "Press coverage or official profiles establishing notability", Required: true, ContentTypes: evidenceContentTypes},
+		{Key: "management_authorization", Description: "Letter from the agent or management running the account on the public figure's behalf", Required: false, ContentTypes: evidenceContentTypes},
+	},
+}
+
+// VerificationChecklist returns the evidence required to verify an account of the given type.
+func VerificationChecklist(accountType string) ([]VerificationRequirement, error) {
+	checklist, ok := verificationChecklists[accountType]
+	if !ok {
+		return nil, fmt.Errorf("account type %q does not support verification", accountType)
+	}
+	return checklist, nil
+}
+
+// EvidenceDocument is a document uploaded in support of a verification request.
+type EvidenceDocument struct {
+	Requirement string            `json:"requirement"`
+	FileName    string            `json:"file_name"`
+	ContentType string            `json:"content_type"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	Content     io.Reader         `json:"-"`
+}
+
+// VerificationDocument represents an evidence document stored with a verification request.
+type VerificationDocument struct {
+	ID          uuid.UUID         `json:"id"`
+	Requirement string            `json:"requirement"`
+	FileName    string            `json:"file_name"`
+	ContentType string            `json:"content_type"`
+	Size        int64             `json:"size"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+// VerificationRequest represents the structure of a verification request.
+type VerificationRequest struct {
+	ID            uuid.UUID              `json:"id"`
+	AccountType   string                 `json:"account_type"`
+	AccountID     uuid.UUID              `json:"account_id"`
+	SubmittedBy   uuid.UUID              `json:"submitted_by"`
+	Status        VerificationStatus     `json:"status"`
+	Notes         string                 `json:"notes,omitempty"`
+	Documents     []VerificationDocument `json:"documents"`
+	ReviewerID    *uuid.UUID             `json:"reviewer_id,omitempty"`
+	ReviewerNotes string                 `json:"reviewer_notes,omitempty"`
+	SubmittedAt   time.Time              `json:"submitted_at"`
+	ReviewedAt    *time.Time             `json:"reviewed_at,omitempty"`
+}
+
+// SubmitVerificationRequestInput represents the input data for submitting a verification request.
+type SubmitVerificationRequestInput struct {
+	AccountType string
+	AccountID   uuid.UUID
+	SubmittedBy uuid.UUID
+	Notes       string
+	Documents   []EvidenceDocument
+}
+
+// ReviewVerificationRequestInput represents the input data for approving or rejecting a verification request.
+type ReviewVerificationRequestInput struct {
+	RequestID  uuid.UUID `json:"-"`
+	ReviewerID uuid.UUID `json:"reviewer_id"`
+	Notes      string    `json:"notes"`
+}
+
+// Validate checks the input against the checklist of its account type.
+func (input *SubmitVerificationRequestInput) Validate() error {
+	checklist, err := VerificationChecklist(input.AccountType)
+	if err != nil {
+		return err
+	}
+	if input.AccountID == uuid.Nil {
+		return errors.New("account ID is required")
+	}
+	if input.SubmittedBy == uuid.Nil {
+		return errors.New("submitter ID is required")
+	}
+
+	requirements := make(map[string]VerificationRequirement, len(checklist))
+	for _, requirement := range checklist {
+		requirements[requirement.Key] = requirement
+	}
+
+	provided := make(map[string]bool)
+	for i, document := range input.Documents {
+		requirement, ok := requirements[document.Requirement]
+		if !ok {
+			return fmt.Errorf("document %d: unknown requirement %q for %s accounts", i, document.Requirement, input.AccountType)
+		}
+		if document.FileName == "" {
+			return fmt.Errorf("document %d: file name is required", i)
+		}
+		if document.Content == nil {
+			return fmt.Errorf("document %d: content is required", i)
+		}
+		accepted := false
+		for _, contentType := range requirement.ContentTypes {
+			if contentType == document.ContentType {
+				accepted = true
+				break
+			}
+		}
+		if !accepted {
+			return fmt.Errorf("document %d: content type %q is not accepted for %s", i, document.ContentType, requirement.Key)
+		}
+		provided[document.Requirement] = true
+	}
+
+	for _, requirement := range checklist {
+		if requirement.Required && !provided[requirement.Key] {
+			return fmt.Errorf("missing required evidence: %s", requirement.Key)
+		}
+	}
+
+	return nil
+}
+
+// Validate validates the ReviewVerificationRequestInput fields.
+func (input *ReviewVerificationRequestInput) Validate() error {
+	if input.RequestID == uuid.Nil {
+		return errors.New("request ID is required")
+	}
+	if input.ReviewerID == uuid.Nil {
+		return errors.New("reviewer ID is required")
+	}
+	return nil
+}
+
+// SubmitVerificationRequest uploads evidence documents for an account and opens a verification request.
+// The documents are sent as a multipart form: a "metadata" JSON part followed by one file part per document.
+func (c *Client) SubmitVerificationRequest(ctx context.Context, input SubmitVerificationRequestInput) (*VerificationRequest, error) {
+	// Validate the input
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Build the multipart body
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	metadata := struct {
+		AccountType string             `json:"account_type"`
+		AccountID   uuid.UUID          `json:"account_id"`
+		SubmittedBy uuid.UUID          `json:"submitted_by"`
+		Notes       string             `json:"notes,omitempty"`
+		Documents   []EvidenceDocument `json:"documents"`
+	}{
+		AccountType: input.AccountType,
+		AccountID:   input.AccountID,
+		SubmittedBy: input.SubmittedBy,
+		Notes:       input.Notes,
+		Documents:   input.Documents,
+	}
+	jsonMetadata, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal metadata: %w", err)
+	}
+	if err := writer.WriteField("metadata", string(jsonMetadata)); err != nil {
+		return nil, fmt.Errorf("unable to write metadata: %w", err)
+	}
+
+	for i, document := range input.Documents {
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="document_%d"; filename=%q`, i, document.FileName))
+		header.Set("Content-Type", document.ContentType)
+		part, err := writer.CreatePart(header)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create document part: %w", err)
+		}
+
+		n, err := io.Copy(part, io.LimitReader(document.Content, maxEvidenceDocumentSize+1))
+		if err != nil {
+			return nil, fmt.Errorf("unable to read document %s: %w", document.FileName, err)
+		}
+		if n > maxEvidenceDocumentSize {
+			return nil, fmt.Errorf("document %s exceeds the %d byte limit", document.FileName, maxEvidenceDocumentSize)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("unable to finish multipart body: %w", err)
+	}
+
+	// Create a new HTTP request
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/api/verification-requests", c.BaseURL), &body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create new request: %w", err)
+	}
+
+	// Set the appropriate headers
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	req.Header.Set("X-API-Key", c.ApiKey)
+
+	// Send the HTTP request
+	res, err := c.HttpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to send request: %w", err)
+	}
+	defer res.Body.Close()
+
+	// Check the status code
+	if res.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(res.Body)
+		return nil, fmt.Errorf("unexpected status code: got %v, body: %s", res.StatusCode, body)
+	}
+
+	// Decode the response body
+	var request VerificationRequest
+	if err := json.NewDecoder(res.Body).Decode(&request); err != nil {
+		return nil, fmt.Errorf("unable to decode response body: %w", err)
+	}
+
+	return &request, nil
+}
+
+// ListPendingVerificationRequests returns the verification requests awaiting review.
+// Pass an empty account type to list pending requests of every kind.
+func (c *Client) ListPendingVerificationRequests(ctx context.Context, accountType string) ([]VerificationRequest, error) {
+	if accountType != "" {
+		if _, err := VerificationChecklist(accountType); err != nil {
+			return nil, err
+		}
+	}
+
+	// Create the URL
+	u, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse base URL: %w", err)
+	}
+	u.Path = path.Join(u.Path, "api", "verification-requests")
+	query := url.Values{}
+	query.Set("status", string(VerificationStatusPending))
+	if accountType != "" {
+		query.Set("account_type", accountType)
+	}
+	u.RawQuery = query.Encode()
+
+	// Create a new HTTP request
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create new request: %w", err)
+	}
+
+	// Set the appropriate headers
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	req.Header.Set("X-API-Key", c.ApiKey)
+
+	// Send the HTTP request
+	res, err := c.HttpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to send request: %w", err)
+	}
+	defer res.Body.Close()
+
+	// Check the status code
+	if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(res.Body)
+		return nil, fmt.Errorf("unexpected status code: got %v, body: %s", res.StatusCode, body)
+	}
+
+	// Decode the response body
+	var requests []VerificationRequest
+	if err := json.NewDecoder(res.Body).Decode(&requests); err != nil {
+		return nil, fmt.Errorf("unable to decode response body: %w", err)
+	}
+
+	return requests, nil
+}
+
+// ApproveVerificationRequest approves a pending verification request. The server marks the
+// account as verified, which shows up as Verified on the Government and Celebrity structs.
+func (c *Client) ApproveVerificationRequest(ctx context.Context, input ReviewVerificationRequestInput) (*VerificationRequest, error) {
+	return c.reviewVerificationRequest(ctx, input, "approve")
+}
+
+// RejectVerificationRequest rejects a pending verification request. Reviewer notes are
+// required so the submitter knows what to fix.
+func (c *Client) RejectVerificationRequest(ctx context.Context, input ReviewVerificationRequestInput) (*VerificationRequest, error) {
+	if input.Notes == "" {
+		return nil, errors.New("reviewer notes are required when rejecting a verification request")
+	}
+	return c.reviewVerificationRequest(ctx, input, "reject")
+}
+
+func (c *Client) reviewVerificationRequest(ctx context.Context, input ReviewVerificationRequestInput, decision string) (*VerificationRequest, error) {
+	// Validate the input
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Marshal the input
+	jsonPayload, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal input: %w", err)
+	}
+
+	// Create the URL
+	u, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse base URL: %w", err)
+	}
+	u.Path = path.Join(u.Path, "api", "verification-requests", input.RequestID.String(), decision)
+
+	// Create a new HTTP request
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewBuffer(jsonPayload))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create new request: %w", err)
+	}
+
+	// Set the appropriate headers
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	req.Header.Set("X-API-Key", c.ApiKey)
+
+	// Send the HTTP request
+	res, err := c.HttpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to send request: %w", err)
+	}
+	defer res.Body.Close()
+
+	// Check the status code
+	if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(res.Body)
+		return nil, fmt.Errorf("unexpected status code: got %v, body: %s", res.StatusCode, body)
+	}
+
+	// Decode the response body
+	var request VerificationRequest
+	if err := json.NewDecoder(res.Body).Decode(&request); err != nil {
+		return nil, fmt.Errorf("unable to decode response body: %w", err)
+	}
+
+	return &request, nil
+}
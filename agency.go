@@ -15,10 +15,11 @@ import (
 
 // Agency represents the structure of an agency.
 type Agency struct {
-	ID            uuid.UUID `json:"id"`
-	UserAccountID uuid.UUID `json:"user_account_id"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	ID            uuid.UUID     `json:"id"`
+	UserAccountID uuid.UUID     `json:"user_account_id"`
+	Status        AccountStatus `json:"status,omitempty"`
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
 }
 
 type CreateAgencyAccountInput struct {
@@ -221,9 +222,14 @@ func (c *Client) DeleteAgencyAccount(agencyID uuid.UUID) error {
 	return nil
 }
 
-func (c *Client) ListAgencyAccounts(userID uuid.UUID) ([]Agency, error) {
+// ListAgencyAccounts lists the agency accounts of a user, optionally restricted to the given statuses.
+func (c *Client) ListAgencyAccounts(userID uuid.UUID, statuses ...AccountStatus) ([]Agency, error) {
 	// Prepare request
-	req, err := http.NewRequest("GET", c.BaseURL+"/agency/accounts/"+userID.String(), nil)
+	endpoint := c.BaseURL + "/agency/accounts/" + userID.String()
+	if len(statuses) > 0 {
+		endpoint += "?" + accountStatusQuery(statuses)
+	}
+	req, err := http.NewRequest("GET", endpoint, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -249,10 +255,23 @@ func (c *Client) ListAgencyAccounts(userID uuid.UUID) ([]Agency, error) {
 		return nil, err
 	}
 
-	return agencyAccounts, nil
+	// Filter by status in case the server ignored the query
+	filtered := agencyAccounts[:0]
+	for _, agency := range agencyAccounts {
+		if matchesAccountStatus(agency.Status, statuses) {
+			filtered = append(filtered, agency)
+		}
+	}
+
+	return filtered, nil
 }
 
 func (c *Client) AddMemberToAgencyAccount(e AddMemberToAgencyAccountEvent) error {
+	// Check the role may be given to the member
+	if err := c.enforceCompanyDomainByRoleName(e.UserID, e.Role, "agency", e.AccountID); err != nil {
+		return err
+	}
+
 	// First, marshal the input data to JSON
 	requestBody, err := json.Marshal(e)
 	if err != nil {
@@ -355,6 +374,11 @@ func (c *Client) GetMembersOfAgencyAccount(agencyID uuid.UUID) ([]AccountMembers
 }
 
 func (c *Client) UpdateMemberRoleInAgencyAccount(input UpdateMemberRoleInAgencyAccountInput) error {
+	// Check the role may be given to the member
+	if err := c.enforceCompanyDomainByRoleID(input.MemberID, input.NewRoleID, "agency", input.AgencyID); err != nil {
+		return err
+	}
+
 	endpoint := fmt.Sprintf("%s/agencies/%s/members/%s", c.BaseURL, input.AgencyID, input.MemberID)
 
 	updateRoleRequest := map[string]interface{}{
@@ -0,0 +1,281 @@
+package accountslib
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// apiKeyPrefix starts every API key, so that leaked keys are easy to recognise in code and logs.
+const apiKeyPrefix = "ak"
+
+var (
+	// ErrAPIKeyNotFound is returned for unknown API keys and key IDs.
+	ErrAPIKeyNotFound = errors.New("API key not found")
+
+	// ErrAPIKeyExpired is returned for API keys that were revoked or are past their expiry.
+	ErrAPIKeyExpired = errors.New("API key has expired or was revoked")
+
+	// ErrAPIKeyScope is returned when an API key would be granted a permission its owner does not have.
+	ErrAPIKeyScope = errors.New("API key scope exceeds the owner's permissions")
+)
+
+// APIKeyOwnerType names the kind of principal an API key acts for.
+type APIKeyOwnerType string
+
+// Owners of API keys.
+const (
+	APIKeyOwnerUser           APIKeyOwnerType = "user"
+	APIKeyOwnerServiceAccount APIKeyOwnerType = "service_account"
+)
+
+// APIKey is a long-lived key sent as X-API-Key by scripts and integrations.
+type APIKey struct {
+	ID        uuid.UUID       `json:"id"`
+	OwnerType APIKeyOwnerType `json:"owner_type"`
+	OwnerID   uuid.UUID       `json:"owner_id"`
+	Name      string          `json:"name"`
+	// Prefix is the non-secret start of the key, shown in listings so users can tell keys apart.
+	Prefix string `json:"prefix"`
+	// Scopes are the names of the permissions the key grants, a subset of its owner's permissions.
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+
+	// Key is the plaintext key, present only in the result of CreateAPIKey.
+	Key string `json:"-"`
+}
+
+// Active reports whether the key can still be used at the given time.
+func (k *APIKey) Active(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
+
+// HasScope reports whether the key grants permission.
+func (k *APIKey) HasScope(permission string) bool {
+	for _, scope := range k.Scopes {
+		if scope == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateAPIKeyInput represents the input data for creating an API key.
+type CreateAPIKeyInput struct {
+	OwnerType APIKeyOwnerType
+	OwnerID   uuid.UUID
+	Name      string
+	// Scopes are permission names; each must be held by the owner.
+	Scopes []string
+	// ExpiresAt is optional; keys without it remain valid until revoked.
+	ExpiresAt *time.Time
+}
+
+// apiKeyPayload is a new API key as sent to the API, with the hash of the key.
+type apiKeyPayload struct {
+	APIKey
+	KeyHash string `json:"key_hash"`
+}
+
+// CreateAPIKey creates an API key for a user or service account. The returned key carries the
+// plaintext Key, which cannot be retrieved again.
+func (c *Client) CreateAPIKey(ctx context.Context, input CreateAPIKeyInput) (*APIKey, error) {
+	// Validate the input
+	if _, err := apiKeyOwnerSegment(input.OwnerType); err != nil {
+		return nil, err
+	}
+	if input.OwnerID == uuid.Nil {
+		return nil, errors.New("owner ID is required")
+	}
+	if strings.TrimSpace(input.Name) == "" {
+		return nil, errors.New("name is required")
+	}
+	if len(input.Scopes) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		return nil, errors.New("expiry must be a future time")
+	}
+
+	// Restrict the scopes to the owner's permissions
+	permissions, err := c.apiKeyOwnerPermissions(ctx, input.OwnerType, input.OwnerID)
+	if err != nil {
+		return nil, err
+	}
+	scopes := make([]string, 0, len(input.Scopes))
+	seen := make(map[string]bool)
+	for _, scope := range input.Scopes {
+		if !permissions[scope] {
+			return nil, fmt.Errorf("%w: %q", ErrAPIKeyScope, scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	sort.Strings(scopes)
+
+	// Generate the key
+	prefix, key, err := generateAPIKey()
+	if err != nil {
+		return nil, err
+	}
+
+	payload := apiKeyPayload{
+		APIKey: APIKey{
+			ID:        uuid.New(),
+			OwnerType: input.OwnerType,
+			OwnerID:   input.OwnerID,
+			Name:      input.Name,
+			Prefix:    prefix,
+			Scopes:    scopes,
+			CreatedAt: time.Now(),
+			ExpiresAt: input.ExpiresAt,
+		},
+		KeyHash: hashSecret(key),
+	}
+
+	var created APIKey
+	if err := c.doJSON(ctx, http.MethodPost, []string{"api-keys"}, payload, http.StatusCreated, &created, ErrAPIKeyNotFound); err != nil {
+		return nil, err
+	}
+	created.Key = key
+
+	return &created, nil
+}
+
+// ListAPIKeys returns the keys of a user or service account, newest first. Revoked and expired
+// keys are included so that they can be shown as such; use Active to tell them apart.
+func (c *Client) ListAPIKeys(ctx context.Context, ownerType APIKeyOwnerType, ownerID uuid.UUID) ([]APIKey, error) {
+	segment, err := apiKeyOwnerSegment(ownerType)
+	if err != nil {
+		return nil, err
+	}
+	if ownerID == uuid.Nil {
+		return nil, errors.New("owner ID is required")
+	}
+
+	var keys []APIKey
+	if err := c.doJSON(ctx, http.MethodGet, []string{segment, ownerID.String(), "api-keys"}, nil, http.StatusOK, &keys, ErrAPIKeyNotFound); err != nil {
+		return nil, err
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.After(keys[j].CreatedAt)
+	})
+
+	return keys, nil
+}
+
+// RevokeAPIKey invalidates an API key immediately.
+func (c *Client) RevokeAPIKey(ctx context.Context, keyID uuid.UUID) error {
+	if keyID == uuid.Nil {
+		return errors.New("API key ID is required")
+	}
+
+	return c.doJSON(ctx, http.MethodDelete, []string{"api-keys", keyID.String()}, nil, http.StatusNoContent, nil, ErrAPIKeyNotFound)
+}
+
+// AuthenticateAPIKey returns the active API key matching a plaintext key, for services that
+// accept API keys from their own callers. The server records the lookup as the key's last use.
+// The returned Scopes are narrowed to the permissions the owner still holds, so a key never
+// outlives a role its owner has lost.
+func (c *Client) AuthenticateAPIKey(ctx context.Context, key string) (*APIKey, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix+"_") {
+		return nil, ErrAPIKeyNotFound
+	}
+
+	var apiKey APIKey
+	if err := c.doJSON(ctx, http.MethodPost, []string{"api-keys", "lookup"}, map[string]string{"key_hash": hashSecret(key)}, http.StatusOK, &apiKey, ErrAPIKeyNotFound); err != nil {
+		return nil, err
+	}
+	if !apiKey.Active(time.Now()) {
+		return nil, ErrAPIKeyExpired
+	}
+
+	// Drop scopes the owner no longer holds
+	permissions, err := c.apiKeyOwnerPermissions(ctx, apiKey.OwnerType, apiKey.OwnerID)
+	if err != nil {
+		return nil, err
+	}
+	scopes := apiKey.Scopes[:0]
+	for _, scope := range apiKey.Scopes {
+		if permissions[scope] {
+			scopes = append(scopes, scope)
+		}
+	}
+	apiKey.Scopes = scopes
+
+	return &apiKey, nil
+}
+
+// apiKeyOwnerPermissions returns the names of the permissions held by a user or service account.
+func (c *Client) apiKeyOwnerPermissions(ctx context.Context, ownerType APIKeyOwnerType, ownerID uuid.UUID) (map[string]bool, error) {
+	var permissions []Permission
+	switch ownerType {
+	case APIKeyOwnerUser:
+		userPermissions, err := c.GetPermissionsByUserID(&GetPermissionsByUserIDInput{UserID: ownerID})
+		if err != nil {
+			return nil, fmt.Errorf("unable to fetch user permissions: %w", err)
+		}
+		permissions = userPermissions
+	case APIKeyOwnerServiceAccount:
+		roles, err := c.GetRolesByServiceAccountID(GetRolesInput{ServiceAccountID: ownerID})
+		if err != nil {
+			return nil, fmt.Errorf("unable to fetch service account roles: %w", err)
+		}
+		for _, role := range roles {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			rolePermissions, err := c.GetPermissionsByRoleID(&GetPermissionsByRoleIDInput{RoleID: role.ID})
+			if err != nil {
+				return nil, fmt.Errorf("unable to fetch permissions of role %s: %w", role.ID, err)
+			}
+			permissions = append(permissions, rolePermissions...)
+		}
+	}
+
+	names := make(map[string]bool, len(permissions))
+	for _, permission := range permissions {
+		names[permission.Name] = true
+	}
+	return names, nil
+}
+
+// apiKeyOwnerSegment returns the API path segment of an owner type.
+func apiKeyOwnerSegment(ownerType APIKeyOwnerType) (string, error) {
+	switch ownerType {
+	case APIKeyOwnerUser:
+		return "users", nil
+	case APIKeyOwnerServiceAccount:
+		return "service-accounts", nil
+	default:
+		return "", fmt.Errorf("unknown API key owner type %q", ownerType)
+	}
+}
+
+// generateAPIKey returns a new key of the form ak_<prefix>_<secret> together with its prefix.
+func generateAPIKey() (string, string, error) {
+	raw := make([]byte, 36)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", fmt.Errorf("unable to generate API key: %w", err)
+	}
+	prefix := hex.EncodeToString(raw[:4])
+	secret := base64.RawURLEncoding.EncodeToString(raw[4:])
+	return prefix, apiKeyPrefix + "_" + prefix + "_" + secret, nil
+}
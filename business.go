@@ -16,10 +16,11 @@ import (
 
 // Business represents the structure of a business.
 type Business struct {
-	ID            uuid.UUID `json:"id"`
-	UserAccountID uuid.UUID `json:"user_account_id"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	ID            uuid.UUID     `json:"id"`
+	UserAccountID uuid.UUID     `json:"user_account_id"`
+	Status        AccountStatus `json:"status,omitempty"`
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
 }
 
 type CreateBusinessAccountInput struct {
@@ -254,7 +255,8 @@ func (c *Client) DeleteBusinessAccount(businessID uuid.UUID) error {
 	return nil
 }
 
-func (c *Client) ListBusinessAccounts() ([]Business, error) {
+// ListBusinessAccounts lists business accounts, optionally restricted to the given statuses.
+func (c *Client) ListBusinessAccounts(statuses ...AccountStatus) ([]Business, error) {
 	// Prepare a new request
 	reqURL, err := url.Parse(c.BaseURL)
 	if err != nil {
@@ -262,6 +264,7 @@ func (c *Client) ListBusinessAccounts() ([]Business, error) {
 	}
 
 	reqURL.Path = path.Join(reqURL.Path, "business-accounts")
+	reqURL.RawQuery = accountStatusQuery(statuses)
 	req, err := http.NewRequest("GET", reqURL.String(), nil)
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %w", err)
@@ -289,7 +292,15 @@ func (c *Client) ListBusinessAccounts() ([]Business, error) {
 		return nil, fmt.Errorf("error decoding response: %w", err)
 	}
 
-	return businessAccounts, nil
+	// Filter by status in case the server ignored the query
+	filtered := businessAccounts[:0]
+	for _, business := range businessAccounts {
+		if matchesAccountStatus(business.Status, statuses) {
+			filtered = append(filtered, business)
+		}
+	}
+
+	return filtered, nil
 }
 
 // Define the validation for AddMemberToBusinessAccountInput
@@ -316,6 +327,11 @@ func (c *Client) AddMemberToBusinessAccount(input AddMemberToBusinessAccountInpu
 		return err
 	}
 
+	// Check the role may be given to the member
+	if err := c.enforceCompanyDomainByRoleID(input.UserID, input.RoleID, "business", input.BusinessID); err != nil {
+		return err
+	}
+
 	requestURL, err := url.Parse(c.BaseURL)
 	if err != nil {
 		return err
@@ -458,6 +474,11 @@ func (c *Client) UpdateMemberRoleInBusinessAccount(input UpdateMemberRoleInBusin
 		return err
 	}
 
+	// Check the role may be given to the member
+	if err := c.enforceCompanyDomainByRoleID(input.MemberUserID, input.NewRoleID, "business", input.BusinessID); err != nil {
+		return err
+	}
+
 	updateURL, err := url.Parse(c.BaseURL)
 	if err != nil {
 		return fmt.Errorf("invalid base url: %w", err)
@@ -16,10 +16,13 @@ import (
 
 // Celebrity represents the structure of an artist, band, sports personality, or other public figure.
 type Celebrity struct {
-	ID            uuid.UUID `json:"id"`
-	UserAccountID uuid.UUID `json:"user_account_id"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	ID            uuid.UUID     `json:"id"`
+	UserAccountID uuid.UUID     `json:"user_account_id"`
+	Status        AccountStatus `json:"status,omitempty"`
+	Verified      bool          `json:"verified"`
+	VerifiedAt    *time.Time    `json:"verified_at,omitempty"`
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
 }
 
 // CreateCelebrityAccountInput represents the information needed to create a celebrity account.
@@ -295,13 +298,15 @@ func (c *Client) DeleteCelebrityAccount(userID uuid.UUID, celebrityID uuid.UUID)
 	return nil
 }
 
-func (c *Client) ListCelebrityAccounts() ([]Celebrity, error) {
+// ListCelebrityAccounts lists celebrity accounts, optionally restricted to the given statuses.
+func (c *Client) ListCelebrityAccounts(statuses ...AccountStatus) ([]Celebrity, error) {
 	// construct the url
 	u, err := url.Parse(c.BaseURL)
 	if err != nil {
 		return nil, err
 	}
 	u.Path = path.Join(u.Path, "celebrities")
+	u.RawQuery = accountStatusQuery(statuses)
 
 	// create the request
 	req, err := http.NewRequest("GET", u.String(), nil)
@@ -333,11 +338,24 @@ func (c *Client) ListCelebrityAccounts() ([]Celebrity, error) {
 		return nil, err
 	}
 
-	return celebrities, nil
+	// filter by status in case the server ignored the query
+	filtered := celebrities[:0]
+	for _, celebrity := range celebrities {
+		if matchesAccountStatus(celebrity.Status, statuses) {
+			filtered = append(filtered, celebrity)
+		}
+	}
+
+	return filtered, nil
 }
 
 // AddMemberToCelebrityAccount adds a new member to a celebrity account.
 func (c *Client) AddMemberToCelebrityAccount(input AddMemberToCelebrityAccountInput) error {
+	// Check the role may be given to the member
+	if err := c.enforceCompanyDomainByRoleID(input.UserID, input.RoleID, "celebrity", input.CelebrityID); err != nil {
+		return err
+	}
+
 	// Create the URL
 	u, err := url.Parse(c.BaseURL)
 	if err != nil {
@@ -459,6 +477,15 @@ func (c *Client) GetMembersOfCelebrityAccount(celebrityID uuid.UUID) ([]AccountM
 }
 
 func (c *Client) UpdateMemberRoleInCelebrityAccount(e *UpdateMemberRoleInCelebrityAccountEvent) error {
+	if e == nil {
+		return errors.New("event is required")
+	}
+
+	// Check the role may be given to the member
+	if err := c.enforceCompanyDomainByRoleName(e.UserID, e.NewRole, "celebrity", e.CelebrityID); err != nil {
+		return err
+	}
+
 	// Step 1: Serialize the data to JSON
 	data, err := json.Marshal(e)
 	if err != nil {
@@ -1,7 +1,15 @@
 package accountslib
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"path"
 	"time"
 )
 
@@ -11,6 +19,19 @@ type Client struct {
 	HttpClient *http.Client
 	Token      string
 	ApiKey     string
+
+	// Mailer sends the emails of the password reset and email change flows.
+	Mailer Mailer
+	// SMSSender sends phone verification codes.
+	SMSSender SMSSender
+	// EmailPolicy restricts the email addresses users can register with or change to.
+	EmailPolicy *EmailPolicy
+	// TokenScopes holds the token scopes the client may issue. Nil means the scopes of DefaultTokenScopes.
+	TokenScopes *TokenScopeRegistry
+	// PasswordPolicy is applied to every new password. Nil means DefaultPasswordPolicy.
+	PasswordPolicy *PasswordPolicy
+	// LocalVerifier, when set, lets Introspect recognise signed JWT access tokens.
+	LocalVerifier *LocalVerifier
 }
 
 func NewClient(baseURL string, token string, apiKey string, httpClient ...*http.Client) *Client {
@@ -30,3 +51,77 @@ func NewClient(baseURL string, token string, apiKey string, httpClient ...*http.
 		ApiKey:     apiKey,
 	}
 }
+
+// unexpectedStatusError is returned by doJSON for responses with a status it was not told to expect.
+type unexpectedStatusError struct {
+	StatusCode int
+	Body       []byte
+}
+
+func (e *unexpectedStatusError) Error() string {
+	return fmt.Sprintf("unexpected status code: got %v, body: %s", e.StatusCode, e.Body)
+}
+
+// hasStatus reports whether err is an unexpectedStatusError for the given status code.
+func hasStatus(err error, statusCode int) bool {
+	var statusErr *unexpectedStatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == statusCode
+}
+
+// doJSON sends payload as JSON to /api/{segments...} and decodes the response into out unless
+// out is nil. A 404 response is reported as notFoundErr when it is set; any other status than
+// expectedStatus yields an unexpectedStatusError.
+func (c *Client) doJSON(ctx context.Context, method string, segments []string, payload interface{}, expectedStatus int, out interface{}, notFoundErr error) error {
+	// Marshal the payload
+	var body io.Reader
+	if payload != nil {
+		jsonPayload, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("unable to marshal payload: %w", err)
+		}
+		body = bytes.NewBuffer(jsonPayload)
+	}
+
+	// Create the URL
+	u, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return fmt.Errorf("unable to parse base URL: %w", err)
+	}
+	u.Path = path.Join(append([]string{u.Path, "api"}, segments...)...)
+
+	// Create a new HTTP request
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return fmt.Errorf("unable to create new request: %w", err)
+	}
+
+	// Set the appropriate headers
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	req.Header.Set("X-API-Key", c.ApiKey)
+
+	// Send the HTTP request
+	res, err := c.HttpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to send request: %w", err)
+	}
+	defer res.Body.Close()
+
+	// Check the status code
+	if res.StatusCode == http.StatusNotFound && notFoundErr != nil {
+		return notFoundErr
+	}
+	if res.StatusCode != expectedStatus {
+		body, _ := io.ReadAll(res.Body)
+		return &unexpectedStatusError{StatusCode: res.StatusCode, Body: body}
+	}
+
+	// Decode the response body
+	if out != nil {
+		if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+			return fmt.Errorf("unable to decode response body: %w", err)
+		}
+	}
+
+	return nil
+}
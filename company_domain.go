@@ -0,0 +1,237 @@
+package accountslib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// ErrCompanyDomainRequired is returned when a role with CompanyDomainOnly set is assigned to a
+// user without a verified email address at one of the company domains.
+var ErrCompanyDomainRequired = errors.New("role requires a verified company email address")
+
+// CompanyDomainMode controls what happens when a CompanyDomainOnly role is assigned to a user
+// outside the company domains.
+type CompanyDomainMode string
+
+const (
+	// CompanyDomainRefuse fails the assignment with ErrCompanyDomainRequired. It is the default.
+	CompanyDomainRefuse CompanyDomainMode = "refuse"
+	// CompanyDomainFlag lets the assignment through and reports the violation.
+	CompanyDomainFlag CompanyDomainMode = "flag"
+	// CompanyDomainOff disables the check.
+	CompanyDomainOff CompanyDomainMode = "off"
+)
+
+// Reasons a user may not hold a CompanyDomainOnly role.
+const (
+	CompanyDomainEmailUnverified = "email_unverified"
+	CompanyDomainEmailOutside    = "email_outside_company_domains"
+)
+
+// CompanyDomainViolation is a user holding, or about to be given, a CompanyDomainOnly role
+// without a verified company email address. AccountType and AccountID are set when the role
+// is held through an account membership rather than assigned to the user directly.
+type CompanyDomainViolation struct {
+	UserID        uuid.UUID `json:"user_id"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`
+	RoleID        uuid.UUID `json:"role_id"`
+	RoleName      string    `json:"role_name"`
+	AccountType   string    `json:"account_type,omitempty"`
+	AccountID     uuid.UUID `json:"account_id"`
+	Reason        string    `json:"reason"`
+}
+
+// checkCompanyDomain returns the violation of user holding role, or nil if there is none.
+func (p *EmailPolicy) checkCompanyDomain(user *User, role *Role) *CompanyDomainViolation {
+	if !role.CompanyDomainOnly {
+		return nil
+	}
+
+	violation := &CompanyDomainViolation{
+		UserID:        user.ID,
+		Email:         user.Email,
+		EmailVerified: user.IsEmailVerified,
+		RoleID:        role.ID,
+		RoleName:      role.Name,
+	}
+	switch {
+	case !p.IsCompanyEmail(user.Email):
+		violation.Reason = CompanyDomainEmailOutside
+	case !user.IsEmailVerified:
+		violation.Reason = CompanyDomainEmailUnverified
+	default:
+		return nil
+	}
+	return violation
+}
+
+// enforceCompanyDomain checks that a user may be given role, according to the client's
+// EmailPolicy. It does nothing unless company domains are configured.
+func (c *Client) enforceCompanyDomain(userID uuid.UUID, role *Role, accountType string, accountID uuid.UUID) error {
+	policy := c.emailPolicy()
+	if !role.CompanyDomainOnly || !policy.enforcesCompanyDomains() {
+		return nil
+	}
+
+	user, err := c.GetUserByID(userID)
+	if err != nil {
+		return fmt.Errorf("unable to fetch user: %w", err)
+	}
+	violation := policy.checkCompanyDomain(user, role)
+	if violation == nil {
+		return nil
+	}
+	violation.AccountType = accountType
+	violation.AccountID = accountID
+
+	if policy.CompanyDomainMode == CompanyDomainFlag {
+		if policy.OnCompanyDomainViolation != nil {
+			policy.OnCompanyDomainViolation(*violation)
+		} else {
+			log.Printf("company domain: role %q assigned to user %s (%s): %s", role.Name, user.ID, user.Email, violation.Reason)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("%w: role %q cannot be given to user %s (%s)", ErrCompanyDomainRequired, role.Name, user.ID, violation.Reason)
+}
+
+// enforcesCompanyDomains reports whether the policy restricts any role assignments, so that
+// callers can skip fetching the role when it does not.
+func (p *EmailPolicy) enforcesCompanyDomains() bool {
+	return len(p.CompanyDomains) > 0 && p.CompanyDomainMode != CompanyDomainOff
+}
+
+// enforceCompanyDomainByRoleID is enforceCompanyDomain for callers that only have the role's ID.
+func (c *Client) enforceCompanyDomainByRoleID(userID uuid.UUID, roleID uuid.UUID, accountType string, accountID uuid.UUID) error {
+	if roleID == uuid.Nil || !c.emailPolicy().enforcesCompanyDomains() {
+		return nil
+	}
+
+	role, err := c.GetRoleByID(roleID)
+	if err != nil {
+		return fmt.Errorf("unable to fetch role: %w", err)
+	}
+	return c.enforceCompanyDomain(userID, role, accountType, accountID)
+}
+
+// enforceCompanyDomainByRoleName is enforceCompanyDomain for memberships, which refer to roles by name.
+func (c *Client) enforceCompanyDomainByRoleName(userID uuid.UUID, roleName string, accountType string, accountID uuid.UUID) error {
+	if roleName == "" || !c.emailPolicy().enforcesCompanyDomains() {
+		return nil
+	}
+
+	role, err := c.GetRoleByName(roleName)
+	if err != nil {
+		return fmt.Errorf("unable to fetch role %q: %w", roleName, err)
+	}
+	return c.enforceCompanyDomain(userID, role, accountType, accountID)
+}
+
+// AuditCompanyDomainRoles lists every user who holds a CompanyDomainOnly role, directly or
+// through an account membership, without a verified email address at one of the company
+// domains of the client's EmailPolicy. It only reads, so it can run whatever the policy's mode.
+func (c *Client) AuditCompanyDomainRoles(ctx context.Context) ([]CompanyDomainViolation, error) {
+	policy := c.emailPolicy()
+	if len(policy.CompanyDomains) == 0 {
+		return nil, errors.New("no company domains configured")
+	}
+
+	// Find the restricted roles
+	roles, err := c.ListRoles()
+	if err != nil {
+		return nil, fmt.Errorf("listing roles: %w", err)
+	}
+	restricted := make(map[uuid.UUID]*Role)
+	restrictedByName := make(map[string]*Role)
+	for i := range roles {
+		if roles[i].CompanyDomainOnly {
+			restricted[roles[i].ID] = &roles[i]
+			restrictedByName[roles[i].Name] = &roles[i]
+		}
+	}
+	if len(restricted) == 0 {
+		return nil, nil
+	}
+
+	users, err := c.ListAllUsers()
+	if err != nil {
+		return nil, fmt.Errorf("listing users: %w", err)
+	}
+	usersByID := make(map[uuid.UUID]*User, len(users))
+	for i := range users {
+		usersByID[users[i].ID] = &users[i]
+	}
+
+	var (
+		mu         sync.Mutex
+		violations []CompanyDomainViolation
+	)
+	record := func(user *User, role *Role, accountType string, accountID uuid.UUID) {
+		violation := policy.checkCompanyDomain(user, role)
+		if violation == nil {
+			return
+		}
+		violation.AccountType = accountType
+		violation.AccountID = accountID
+
+		mu.Lock()
+		defer mu.Unlock()
+		violations = append(violations, *violation)
+	}
+
+	// Check roles assigned directly, and roles held through memberships
+	fetcher := &concurrentFetcher{ctx: ctx, sem: make(chan struct{}, maxConcurrentFetches)}
+	for i := range users {
+		user := &users[i]
+		fetcher.run(func() error {
+			userRoles, err := c.GetRolesForUser(user.ID)
+			if err != nil {
+				return fmt.Errorf("fetching roles of user %s: %w", user.ID, err)
+			}
+			for _, userRole := range userRoles {
+				if role, ok := restricted[userRole.ID]; ok {
+					record(user, role, "", uuid.Nil)
+				}
+			}
+			return nil
+		})
+	}
+	fetcher.run(func() error {
+		memberships, err := c.ListAccountMemberships(uuid.Nil)
+		if err != nil {
+			return fmt.Errorf("listing account memberships: %w", err)
+		}
+		for _, membership := range memberships {
+			role, ok := restrictedByName[membership.Role]
+			user, known := usersByID[membership.UserID]
+			if ok && known {
+				record(user, role, membership.AccountType, membership.AccountID)
+			}
+		}
+		return nil
+	})
+	if err := fetcher.wait(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(violations, func(i, j int) bool {
+		a, b := violations[i], violations[j]
+		if a.Email != b.Email {
+			return a.Email < b.Email
+		}
+		if a.RoleName != b.RoleName {
+			return a.RoleName < b.RoleName
+		}
+		return a.AccountID.String() < b.AccountID.String()
+	})
+
+	return violations, nil
+}
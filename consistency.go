@@ -0,0 +1,471 @@
+package accountslib
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// InconsistencyCategory classifies a problem found by AuditAccountConsistency.
+type InconsistencyCategory string
+
+const (
+	// InconsistencyMembershipOrphanedAccount is a membership whose account no longer exists.
+	InconsistencyMembershipOrphanedAccount InconsistencyCategory = "membership_orphaned_account"
+	// InconsistencyMembershipOrphanedUser is a membership whose user no longer exists.
+	InconsistencyMembershipOrphanedUser InconsistencyCategory = "membership_orphaned_user"
+	// InconsistencyMembershipTypeMismatch is a membership whose AccountType does not match its AccountID.
+	InconsistencyMembershipTypeMismatch InconsistencyCategory = "membership_account_type_mismatch"
+	// InconsistencyMembershipDuplicate is a second membership of the same user in the same account.
+	InconsistencyMembershipDuplicate InconsistencyCategory = "membership_duplicate"
+	// InconsistencyLinkOrphanedAccount is a link whose account no longer exists.
+	InconsistencyLinkOrphanedAccount InconsistencyCategory = "link_orphaned_account"
+	// InconsistencyLinkOrphanedUser is a link whose user no longer exists.
+	InconsistencyLinkOrphanedUser InconsistencyCategory = "link_orphaned_user"
+	// InconsistencyLinkTypeMismatch is a link whose AccountType does not match its AccountID.
+	InconsistencyLinkTypeMismatch InconsistencyCategory = "link_account_type_mismatch"
+)
+
+// Repair actions planned or taken by ReconcileAccountConsistency.
+const (
+	RepairDeleteMembership  = "delete_membership"
+	RepairUpdateMembership  = "update_membership_account_type"
+	RepairDeleteAccountLink = "delete_account_link"
+	RepairUpdateAccountLink = "update_account_link_account_type"
+	RepairManual            = "manual"
+)
+
+// Inconsistency is a single problem found by AuditAccountConsistency.
+// Exactly one of Membership and Link is set.
+type Inconsistency struct {
+	Category            InconsistencyCategory `json:"category"`
+	Description         string                `json:"description"`
+	Membership          *AccountMembership    `json:"membership,omitempty"`
+	Link                *AccountLink          `json:"link,omitempty"`
+	ExpectedAccountType string                `json:"expected_account_type,omitempty"`
+	Repair              string                `json:"repair"`
+}
+
+// ConsistencyReport is the result of AuditAccountConsistency.
+type ConsistencyReport struct {
+	ScannedAt       time.Time       `json:"scanned_at"`
+	Users           int             `json:"users"`
+	Accounts        int             `json:"accounts"`
+	Memberships     int             `json:"memberships"`
+	Links           int             `json:"links"`
+	Inconsistencies []Inconsistency `json:"inconsistencies"`
+}
+
+// ByCategory counts the inconsistencies in each category.
+func (r *ConsistencyReport) ByCategory() map[InconsistencyCategory]int {
+	counts := make(map[InconsistencyCategory]int)
+	for _, inconsistency := range r.Inconsistencies {
+		counts[inconsistency.Category]++
+	}
+	return counts
+}
+
+// ReconcileOptions controls ReconcileAccountConsistency.
+type ReconcileOptions struct {
+	// Apply performs the repairs. When false, which is the default, the planned
+	// repairs are only reported.
+	Apply bool
+	// Categories restricts repairs to the given categories. Empty repairs every category.
+	Categories []InconsistencyCategory
+}
+
+// RepairResult is the outcome of repairing one inconsistency.
+type RepairResult struct {
+	Inconsistency Inconsistency `json:"inconsistency"`
+	Applied       bool          `json:"applied"`
+	Error         string        `json:"error,omitempty"`
+}
+
+// consistencySnapshot is everything AuditAccountConsistency needs, fetched up front.
+type consistencySnapshot struct {
+	users       map[uuid.UUID]bool
+	accounts    map[uuid.UUID]string
+	memberships []AccountMembership
+	links       []AccountLink
+}
+
+// AuditAccountConsistency scans users, organization accounts, memberships and account links
+// and reports every membership or link that points at something missing or mistyped.
+// Agencies are only listable per user, so an agency that no listed user owns, such as one whose
+// owner was soft-deleted, is looked up directly before anything pointing at it is reported.
+func (c *Client) AuditAccountConsistency(ctx context.Context) (*ConsistencyReport, error) {
+	snapshot, err := c.fetchConsistencySnapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ConsistencyReport{
+		ScannedAt:   time.Now(),
+		Users:       len(snapshot.users),
+		Accounts:    len(snapshot.accounts),
+		Memberships: len(snapshot.memberships),
+		Links:       len(snapshot.links),
+	}
+
+	// Check memberships
+	seen := make(map[[2]uuid.UUID]bool)
+	for i := range snapshot.memberships {
+		membership := &snapshot.memberships[i]
+
+		key := [2]uuid.UUID{membership.UserID, membership.AccountID}
+		if seen[key] {
+			report.Inconsistencies = append(report.Inconsistencies, Inconsistency{
+				Category:    InconsistencyMembershipDuplicate,
+				Description: fmt.Sprintf("user %s has more than one membership in account %s", membership.UserID, membership.AccountID),
+				Membership:  membership,
+				Repair:      RepairManual,
+			})
+			continue
+		}
+		seen[key] = true
+
+		if !snapshot.users[membership.UserID] {
+			report.Inconsistencies = append(report.Inconsistencies, Inconsistency{
+				Category:    InconsistencyMembershipOrphanedUser,
+				Description: fmt.Sprintf("membership %s belongs to missing user %s", membership.ID, membership.UserID),
+				Membership:  membership,
+				Repair:      RepairDeleteMembership,
+			})
+			continue
+		}
+
+		actualType, ok := snapshot.accounts[membership.AccountID]
+		switch {
+		case !ok:
+			report.Inconsistencies = append(report.Inconsistencies, Inconsistency{
+				Category:    InconsistencyMembershipOrphanedAccount,
+				Description: fmt.Sprintf("membership %s points at missing %s account %s", membership.ID, membership.AccountType, membership.AccountID),
+				Membership:  membership,
+				Repair:      RepairDeleteMembership,
+			})
+		case actualType != membership.AccountType:
+			report.Inconsistencies = append(report.Inconsistencies, Inconsistency{
+				Category:            InconsistencyMembershipTypeMismatch,
+				Description:         fmt.Sprintf("membership %s says %s but account %s is a %s account", membership.ID, membership.AccountType, membership.AccountID, actualType),
+				Membership:          membership,
+				ExpectedAccountType: actualType,
+				Repair:              RepairUpdateMembership,
+			})
+		}
+	}
+
+	// Check account links
+	for i := range snapshot.links {
+		link := &snapshot.links[i]
+
+		if !snapshot.users[link.UserID] {
+			report.Inconsistencies = append(report.Inconsistencies, Inconsistency{
+				Category:    InconsistencyLinkOrphanedUser,
+				Description: fmt.Sprintf("link to %s account %s belongs to missing user %s", link.AccountType, link.AccountID, link.UserID),
+				Link:        link,
+				Repair:      RepairDeleteAccountLink,
+			})
+			continue
+		}
+
+		actualType, ok := snapshot.accounts[link.AccountID]
+		switch {
+		case !ok:
+			report.Inconsistencies = append(report.Inconsistencies, Inconsistency{
+				Category:    InconsistencyLinkOrphanedAccount,
+				Description: fmt.Sprintf("user %s is linked to missing %s account %s", link.UserID, link.AccountType, link.AccountID),
+				Link:        link,
+				Repair:      RepairDeleteAccountLink,
+			})
+		case actualType != link.AccountType:
+			report.Inconsistencies = append(report.Inconsistencies, Inconsistency{
+				Category:            InconsistencyLinkTypeMismatch,
+				Description:         fmt.Sprintf("link of user %s says %s but account %s is a %s account", link.UserID, link.AccountType, link.AccountID, actualType),
+				Link:                link,
+				ExpectedAccountType: actualType,
+				Repair:              RepairUpdateAccountLink,
+			})
+		}
+	}
+
+	sort.SliceStable(report.Inconsistencies, func(i, j int) bool {
+		return report.Inconsistencies[i].Category < report.Inconsistencies[j].Category
+	})
+
+	return report, nil
+}
+
+// ReconcileAccountConsistency repairs the inconsistencies of a report. Unless opts.Apply is set
+// nothing is changed and the returned results describe what would be done.
+// Inconsistencies that need a human decision are never repaired automatically.
+func (c *Client) ReconcileAccountConsistency(ctx context.Context, report *ConsistencyReport, opts ReconcileOptions) ([]RepairResult, error) {
+	if report == nil {
+		return nil, fmt.Errorf("report is required")
+	}
+
+	wanted := make(map[InconsistencyCategory]bool, len(opts.Categories))
+	for _, category := range opts.Categories {
+		wanted[category] = true
+	}
+
+	var results []RepairResult
+	for _, inconsistency := range report.Inconsistencies {
+		if len(wanted) > 0 && !wanted[inconsistency.Category] {
+			continue
+		}
+		if inconsistency.Repair == RepairManual {
+			results = append(results, RepairResult{Inconsistency: inconsistency})
+			continue
+		}
+		if !opts.Apply {
+			results = append(results, RepairResult{Inconsistency: inconsistency})
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+
+		result := RepairResult{Inconsistency: inconsistency}
+		if err := c.repairInconsistency(inconsistency); err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Applied = true
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+func (c *Client) repairInconsistency(inconsistency Inconsistency) error {
+	membership, link := inconsistency.Membership, inconsistency.Link
+
+	switch inconsistency.Repair {
+	case RepairDeleteMembership:
+		return c.DeleteAccountMembership(membership.AccountID, membership.UserID)
+	case RepairUpdateMembership:
+		_, err := c.UpdateAccountMembership(membership.ID, UpdateAccountMembershipEvent{AccountType: inconsistency.ExpectedAccountType})
+		return err
+	case RepairDeleteAccountLink:
+		return c.DeleteAccountLink(&AccountLinkRequest{UserID: link.UserID, AccountType: link.AccountType, AccountID: link.AccountID})
+	case RepairUpdateAccountLink:
+		return c.UpdateAccountLink(link.UserID, inconsistency.ExpectedAccountType, link.AccountID)
+	default:
+		return fmt.Errorf("no automatic repair for %s", inconsistency.Category)
+	}
+}
+
+func (c *Client) fetchConsistencySnapshot(ctx context.Context) (*consistencySnapshot, error) {
+	snapshot := &consistencySnapshot{
+		users:    make(map[uuid.UUID]bool),
+		accounts: make(map[uuid.UUID]string),
+	}
+
+	var mu sync.Mutex
+	addAccounts := func(accountType string, ids []uuid.UUID) {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, id := range ids {
+			snapshot.accounts[id] = accountType
+		}
+	}
+
+	// Users are needed before agencies and per-user links can be listed
+	users, err := c.ListAllUsers()
+	if err != nil {
+		return nil, fmt.Errorf("listing users: %w", err)
+	}
+	userIDs := make([]uuid.UUID, len(users))
+	for i, user := range users {
+		userIDs[i] = user.ID
+		snapshot.users[user.ID] = true
+	}
+	addAccounts("user", userIDs)
+
+	fetcher := &concurrentFetcher{ctx: ctx, sem: make(chan struct{}, maxConcurrentFetches)}
+
+	fetcher.run(func() error {
+		businesses, err := c.ListBusinessAccounts()
+		if err != nil {
+			return fmt.Errorf("listing business accounts: %w", err)
+		}
+		ids := make([]uuid.UUID, len(businesses))
+		for i, business := range businesses {
+			ids[i] = business.ID
+		}
+		addAccounts("business", ids)
+		return nil
+	})
+	fetcher.run(func() error {
+		celebrities, err := c.ListCelebrityAccounts()
+		if err != nil {
+			return fmt.Errorf("listing celebrity accounts: %w", err)
+		}
+		ids := make([]uuid.UUID, len(celebrities))
+		for i, celebrity := range celebrities {
+			ids[i] = celebrity.ID
+		}
+		addAccounts("celebrity", ids)
+		return nil
+	})
+	fetcher.run(func() error {
+		enterprises, err := c.ListEnterpriseAccounts()
+		if err != nil {
+			return fmt.Errorf("listing enterprise accounts: %w", err)
+		}
+		ids := make([]uuid.UUID, len(enterprises))
+		for i, enterprise := range enterprises {
+			ids[i] = enterprise.ID
+		}
+		addAccounts("enterprise", ids)
+		return nil
+	})
+	fetcher.run(func() error {
+		governments, err := c.ListGovernmentAccounts()
+		if err != nil {
+			return fmt.Errorf("listing government accounts: %w", err)
+		}
+		ids := make([]uuid.UUID, len(governments))
+		for i, government := range governments {
+			ids[i] = government.ID
+		}
+		addAccounts("government", ids)
+		return nil
+	})
+	fetcher.run(func() error {
+		memberships, err := c.ListAccountMemberships(uuid.Nil)
+		if err != nil {
+			return fmt.Errorf("listing account memberships: %w", err)
+		}
+		mu.Lock()
+		snapshot.memberships = memberships
+		mu.Unlock()
+		return nil
+	})
+
+	// Links are listed per user and per organization type so links of deleted users are found too
+	links := make(map[AccountLinkRequest]AccountLink)
+	addLinks := func(found []AccountLink) {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, link := range found {
+			links[AccountLinkRequest{UserID: link.UserID, AccountType: link.AccountType, AccountID: link.AccountID}] = link
+		}
+	}
+	for _, accountType := range organizationAccountTypes {
+		accountType := accountType.(string)
+		fetcher.run(func() error {
+			found, err := c.GetAccountLinksByAccountType(accountType)
+			if err != nil {
+				return fmt.Errorf("listing %s account links: %w", accountType, err)
+			}
+			addLinks(found)
+			return nil
+		})
+	}
+	for _, userID := range userIDs {
+		fetcher.run(func() error {
+			found, err := c.ListAccountLinks(userID)
+			if err != nil {
+				return fmt.Errorf("listing account links of user %s: %w", userID, err)
+			}
+			addLinks(found)
+			return nil
+		})
+		fetcher.run(func() error {
+			agencies, err := c.ListAgencyAccounts(userID)
+			if err != nil {
+				return fmt.Errorf("listing agency accounts of user %s: %w", userID, err)
+			}
+			ids := make([]uuid.UUID, len(agencies))
+			for i, agency := range agencies {
+				ids[i] = agency.ID
+			}
+			addAccounts("agency", ids)
+			return nil
+		})
+	}
+
+	if err := fetcher.wait(); err != nil {
+		return nil, err
+	}
+
+	// Look up the agencies that were referenced but not listed
+	unlisted := make(map[uuid.UUID]bool)
+	for _, membership := range snapshot.memberships {
+		if _, ok := snapshot.accounts[membership.AccountID]; !ok && membership.AccountType == "agency" {
+			unlisted[membership.AccountID] = true
+		}
+	}
+	for _, link := range links {
+		if _, ok := snapshot.accounts[link.AccountID]; !ok && link.AccountType == "agency" {
+			unlisted[link.AccountID] = true
+		}
+	}
+	fetcher = &concurrentFetcher{ctx: ctx, sem: make(chan struct{}, maxConcurrentFetches)}
+	for agencyID := range unlisted {
+		fetcher.run(func() error {
+			found, err := c.agencyExists(ctx, agencyID)
+			if err != nil {
+				return fmt.Errorf("looking up agency account %s: %w", agencyID, err)
+			}
+			if found {
+				addAccounts("agency", []uuid.UUID{agencyID})
+			}
+			return nil
+		})
+	}
+	if err := fetcher.wait(); err != nil {
+		return nil, err
+	}
+
+	for _, link := range links {
+		snapshot.links = append(snapshot.links, link)
+	}
+	sort.Slice(snapshot.links, func(i, j int) bool {
+		if snapshot.links[i].UserID != snapshot.links[j].UserID {
+			return snapshot.links[i].UserID.String() < snapshot.links[j].UserID.String()
+		}
+		return snapshot.links[i].AccountID.String() < snapshot.links[j].AccountID.String()
+	})
+
+	return snapshot, nil
+}
+
+// agencyExists reports whether an agency account exists, whether or not its owner does.
+func (c *Client) agencyExists(ctx context.Context, agencyID uuid.UUID) (bool, error) {
+	// Create a new HTTP request
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/agency/%s", c.BaseURL, agencyID), nil)
+	if err != nil {
+		return false, fmt.Errorf("unable to create new request: %w", err)
+	}
+
+	// Set the appropriate headers
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	req.Header.Set("X-API-Key", c.ApiKey)
+
+	// Send the HTTP request
+	res, err := c.HttpClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("unable to send request: %w", err)
+	}
+	defer res.Body.Close()
+
+	// Check the status code
+	switch res.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		body, _ := io.ReadAll(res.Body)
+		return false, fmt.Errorf("unexpected status code: got %v, body: %s", res.StatusCode, body)
+	}
+}
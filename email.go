@@ -0,0 +1,289 @@
+package accountslib
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/google/uuid"
+	"golang.org/x/net/idna"
+)
+
+var (
+	// ErrInvalidEmail is returned for addresses that cannot be normalized.
+	ErrInvalidEmail = errors.New("invalid email")
+
+	// ErrDisposableEmail is returned for addresses at a domain on the disposable domain blocklist.
+	ErrDisposableEmail = errors.New("disposable email addresses are not allowed")
+
+	// ErrDuplicateEmail is returned when another account's address has the same canonical form.
+	ErrDuplicateEmail = errors.New("an account with an equivalent email address already exists")
+)
+
+// Length limits of an email address from RFC 5321.
+const (
+	maxEmailLength      = 254
+	maxEmailLocalLength = 64
+	maxDomainLength     = 253
+	maxDomainLabel      = 63
+)
+
+// emailLocalSpecials are the characters besides letters and digits allowed in an unquoted local part.
+const emailLocalSpecials = "!#$%&'*+/=?^_`{|}~-."
+
+// DefaultDisposableDomains is a starter blocklist of well known disposable email providers.
+// It is not used unless it is set as an EmailPolicy's DisposableDomains.
+var DefaultDisposableDomains = []string{
+	"10minutemail.com",
+	"dispostable.com",
+	"getnada.com",
+	"guerrillamail.com",
+	"maildrop.cc",
+	"mailinator.com",
+	"sharklasers.com",
+	"temp-mail.org",
+	"throwawaymail.com",
+	"trashmail.com",
+	"yopmail.com",
+}
+
+// EmailPolicy restricts the addresses the client accepts beyond NormalizeEmail's syntax checks.
+// Domains in either list also match their subdomains.
+type EmailPolicy struct {
+	// DisposableDomains are rejected by Normalize.
+	DisposableDomains []string
+
+	// RejectCanonicalDuplicates refuses registrations and email changes with ErrDuplicateEmail
+	// when another account's address has the same CanonicalizeEmail form, so that
+	// "jane+x@gmail.com" cannot open a second account next to "Jane@gmail.com".
+	RejectCanonicalDuplicates bool
+
+	// CompanyDomains are the organisation's own domains. Only users with a verified address
+	// at one of these domains may be given roles with CompanyDomainOnly set. The check is
+	// skipped when no company domains are configured.
+	CompanyDomains []string
+
+	// CompanyDomainMode decides whether assignments that break the CompanyDomains rule are
+	// refused or only flagged. Empty means CompanyDomainRefuse.
+	CompanyDomainMode CompanyDomainMode
+
+	// OnCompanyDomainViolation is called for assignments let through by CompanyDomainFlag.
+	// When nil, they are logged.
+	OnCompanyDomainViolation func(violation CompanyDomainViolation)
+}
+
+// NormalizeEmail validates an email address and returns its normal form: surrounding space
+// removed, the whole address lower cased, and an internationalized domain converted to its
+// ASCII (punycode) form. Two addresses that differ only in case or in how the domain is
+// written normalize to the same string.
+func NormalizeEmail(email string) (string, error) {
+	email = strings.TrimSpace(email)
+	at := strings.LastIndex(email, "@")
+	if at <= 0 || at == len(email)-1 {
+		return "", fmt.Errorf("%w: address must have the form local@domain", ErrInvalidEmail)
+	}
+
+	local, err := normalizeEmailLocal(email[:at])
+	if err != nil {
+		return "", err
+	}
+	domain, err := normalizeEmailDomain(email[at+1:])
+	if err != nil {
+		return "", err
+	}
+
+	normalized := local + "@" + domain
+	if len(normalized) > maxEmailLength {
+		return "", fmt.Errorf("%w: address is longer than %d characters", ErrInvalidEmail, maxEmailLength)
+	}
+
+	return normalized, nil
+}
+
+// ValidateEmail reports whether email is a syntactically valid address.
+func ValidateEmail(email string) error {
+	_, err := NormalizeEmail(email)
+	return err
+}
+
+// CanonicalizeEmail returns the form of an address used to detect duplicate accounts. On top of
+// NormalizeEmail it removes a plus-address tag ("jane+news@example.com" becomes
+// "jane@example.com"), and for Gmail it also removes dots from the local part and maps
+// googlemail.com to gmail.com, since Gmail delivers all of those to the same mailbox. The
+// canonical form is for comparison only; mail should still be sent to the normalized address.
+// EmailPolicy.RejectCanonicalDuplicates uses it to refuse duplicate accounts.
+func CanonicalizeEmail(email string) (string, error) {
+	normalized, err := NormalizeEmail(email)
+	if err != nil {
+		return "", err
+	}
+
+	at := strings.LastIndex(normalized, "@")
+	local, domain := normalized[:at], normalized[at+1:]
+	if plus := strings.Index(local, "+"); plus > 0 {
+		local = local[:plus]
+	}
+	if domain == "gmail.com" || domain == "googlemail.com" {
+		local = strings.ReplaceAll(local, ".", "")
+		domain = "gmail.com"
+	}
+
+	return local + "@" + domain, nil
+}
+
+// Normalize normalizes email with NormalizeEmail and rejects disposable addresses.
+func (p *EmailPolicy) Normalize(email string) (string, error) {
+	normalized, err := NormalizeEmail(email)
+	if err != nil {
+		return "", err
+	}
+	if emailDomainInList(normalized, p.DisposableDomains) {
+		return "", ErrDisposableEmail
+	}
+	return normalized, nil
+}
+
+// IsCompanyEmail reports whether email is at one of the policy's company domains.
+func (p *EmailPolicy) IsCompanyEmail(email string) bool {
+	normalized, err := NormalizeEmail(email)
+	if err != nil {
+		return false
+	}
+	return emailDomainInList(normalized, p.CompanyDomains)
+}
+
+// emailPolicy returns the client's configured email policy, or an empty policy.
+func (c *Client) emailPolicy() *EmailPolicy {
+	if c.EmailPolicy != nil {
+		return c.EmailPolicy
+	}
+	return &EmailPolicy{}
+}
+
+// checkCanonicalDuplicate returns ErrDuplicateEmail when the policy rejects canonical duplicates
+// and a user other than userID has an address with the same canonical form as email.
+func (c *Client) checkCanonicalDuplicate(email string, userID uuid.UUID) error {
+	if !c.emailPolicy().RejectCanonicalDuplicates {
+		return nil
+	}
+
+	canonical, err := CanonicalizeEmail(email)
+	if err != nil {
+		return err
+	}
+	existing, err := c.getUserByCanonicalEmail(canonical)
+	if err != nil {
+		return fmt.Errorf("unable to check for duplicate email: %w", err)
+	}
+	if existing != nil && existing.ID != userID {
+		return ErrDuplicateEmail
+	}
+	return nil
+}
+
+// LoadEmailDomainList reads a domain list with one domain per line, such as a published
+// disposable domain blocklist. Blank lines and lines starting with # are skipped.
+func LoadEmailDomainList(r io.Reader) ([]string, error) {
+	var domains []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		domain, err := normalizeEmailDomain(line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid domain %q: %w", line, err)
+		}
+		domains = append(domains, domain)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return domains, nil
+}
+
+// emailRule validates string and *string fields holding an email address with NormalizeEmail.
+var emailRule = validation.By(func(value interface{}) error {
+	value, isNil := validation.Indirect(value)
+	if isNil || validation.IsEmpty(value) {
+		return nil
+	}
+	email, ok := value.(string)
+	if !ok {
+		return errors.New("must be a string")
+	}
+	return ValidateEmail(email)
+})
+
+// normalizeEmailLocal checks and lower cases the local part of an address. Quoted local parts
+// are not accepted. Non-ASCII characters are, as allowed by RFC 6531.
+func normalizeEmailLocal(local string) (string, error) {
+	if len(local) > maxEmailLocalLength {
+		return "", fmt.Errorf("%w: local part is longer than %d characters", ErrInvalidEmail, maxEmailLocalLength)
+	}
+	if !utf8.ValidString(local) {
+		return "", fmt.Errorf("%w: local part is not valid UTF-8", ErrInvalidEmail)
+	}
+	if strings.HasPrefix(local, ".") || strings.HasSuffix(local, ".") || strings.Contains(local, "..") {
+		return "", fmt.Errorf("%w: misplaced dot in local part", ErrInvalidEmail)
+	}
+	for _, r := range local {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r < utf8.RuneSelf && strings.ContainsRune(emailLocalSpecials, r):
+		case r >= utf8.RuneSelf:
+		default:
+			return "", fmt.Errorf("%w: unexpected character %q in local part", ErrInvalidEmail, r)
+		}
+	}
+	return strings.ToLower(local), nil
+}
+
+// normalizeEmailDomain converts a domain to lower case ASCII, punycode encoding internationalized
+// labels, and checks it is a valid host name with at least two labels.
+func normalizeEmailDomain(domain string) (string, error) {
+	ascii, err := idna.Lookup.ToASCII(strings.TrimSuffix(domain, "."))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidEmail, err)
+	}
+	if len(ascii) > maxDomainLength {
+		return "", fmt.Errorf("%w: domain is longer than %d characters", ErrInvalidEmail, maxDomainLength)
+	}
+
+	labels := strings.Split(ascii, ".")
+	if len(labels) < 2 {
+		return "", fmt.Errorf("%w: domain must have at least two labels", ErrInvalidEmail)
+	}
+	for _, label := range labels {
+		if label == "" || len(label) > maxDomainLabel {
+			return "", fmt.Errorf("%w: invalid domain label %q", ErrInvalidEmail, label)
+		}
+	}
+	tld := labels[len(labels)-1]
+	if !strings.HasPrefix(tld, "xn--") && (len(tld) < 2 || strings.Trim(tld, "abcdefghijklmnopqrstuvwxyz") != "") {
+		return "", fmt.Errorf("%w: invalid top level domain %q", ErrInvalidEmail, tld)
+	}
+
+	return ascii, nil
+}
+
+// emailDomainInList reports whether the domain of a normalized address is, or is a subdomain
+// of, one of domains.
+func emailDomainInList(normalized string, domains []string) bool {
+	domain := normalized[strings.LastIndex(normalized, "@")+1:]
+	for _, listed := range domains {
+		listed, err := normalizeEmailDomain(listed)
+		if err != nil {
+			continue
+		}
+		if domain == listed || strings.HasSuffix(domain, "."+listed) {
+			return true
+		}
+	}
+	return false
+}
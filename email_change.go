@@ -0,0 +1,213 @@
+package accountslib
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrNoPendingEmailChange is returned when a user has no email change in progress.
+	ErrNoPendingEmailChange = errors.New("no pending email change")
+
+	// ErrInvalidEmailChangeToken is returned for every unusable confirmation token, whether it is
+	// unknown, expired, issued for another scope or for an email change that was since cancelled.
+	ErrInvalidEmailChangeToken = errors.New("invalid or expired email change token")
+)
+
+// PendingEmailChange is an email change that is waiting for both addresses to be confirmed.
+type PendingEmailChange struct {
+	UserID           uuid.UUID `json:"user_id"`
+	CurrentEmail     string    `json:"current_email"`
+	NewEmail         string    `json:"new_email"`
+	CurrentConfirmed bool      `json:"current_confirmed"`
+	NewConfirmed     bool      `json:"new_confirmed"`
+	RequestedAt      time.Time `json:"requested_at"`
+	ExpiresAt        time.Time `json:"expires_at"`
+}
+
+// Confirmed reports whether both the current and the new address have been confirmed.
+func (p *PendingEmailChange) Confirmed() bool {
+	return p.CurrentConfirmed && p.NewConfirmed
+}
+
+// pendingEmailChangePayload is the pending change as stored by the API. The token hashes bind
+// the change to the tokens that were emailed for it, so tokens from an earlier, cancelled or
+// superseded change cannot confirm it.
+type pendingEmailChangePayload struct {
+	CurrentEmail     string    `json:"current_email"`
+	NewEmail         string    `json:"new_email"`
+	CurrentTokenHash string    `json:"current_token_hash"`
+	NewTokenHash     string    `json:"new_token_hash"`
+	ExpiresAt        time.Time `json:"expires_at"`
+}
+
+// RequestEmailChange starts changing a user's email address to newEmail. A confirmation token
+// is emailed to both the current and the new address, and the change is only applied by
+// ConfirmEmailChange once both have been confirmed. Requesting a new change replaces any
+// change that is still pending.
+func (c *Client) RequestEmailChange(ctx context.Context, userID uuid.UUID, newEmail string) (*PendingEmailChange, error) {
+	// Validate the input
+	if userID == uuid.Nil {
+		return nil, errors.New("user ID is required")
+	}
+	newEmail, err := c.emailPolicy().Normalize(newEmail)
+	if err != nil {
+		return nil, err
+	}
+	if c.Mailer == nil {
+		return nil, errors.New("no mailer configured")
+	}
+
+	// Check the user and the new address
+	user, err := c.GetUserByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch user: %w", err)
+	}
+	if currentEmail, err := NormalizeEmail(user.Email); err == nil && currentEmail == newEmail {
+		return nil, errors.New("new email is the same as the current email")
+	}
+	if existing, err := c.GetUserByEmail(newEmail); err == nil && existing != nil && existing.ID != userID {
+		return nil, errors.New("email is already in use")
+	}
+	if err := c.checkCanonicalDuplicate(newEmail, userID); err != nil {
+		return nil, err
+	}
+
+	// Create one token for each address
+	currentToken, err := c.CreateToken(CreateTokenInput{UserID: userID, Scope: ScopeEmailChange})
+	if err != nil {
+		return nil, fmt.Errorf("unable to create token: %w", err)
+	}
+	newToken, err := c.CreateToken(CreateTokenInput{UserID: userID, Scope: ScopeEmailChange})
+	if err != nil {
+		return nil, fmt.Errorf("unable to create token: %w", err)
+	}
+
+	// Store the pending change
+	expiresAt := currentToken.Expiry
+	if newToken.Expiry.Before(expiresAt) {
+		expiresAt = newToken.Expiry
+	}
+	payload := pendingEmailChangePayload{
+		CurrentEmail:     user.Email,
+		NewEmail:         newEmail,
+		CurrentTokenHash: hex.EncodeToString(currentToken.Hash),
+		NewTokenHash:     hex.EncodeToString(newToken.Hash),
+		ExpiresAt:        expiresAt,
+	}
+	var pending PendingEmailChange
+	if err := c.doJSON(ctx, http.MethodPut, []string{"users", userID.String(), "email-change"}, payload, http.StatusOK, &pending, ErrNoPendingEmailChange); err != nil {
+		return nil, err
+	}
+
+	// Email both addresses
+	messages := []MailMessage{
+		{To: user.Email, Template: MailTemplateEmailChangeCurrent, Data: emailChangeMailData(currentToken, newEmail)},
+		{To: newEmail, Template: MailTemplateEmailChangeNew, Data: emailChangeMailData(newToken, newEmail)},
+	}
+	for _, message := range messages {
+		if err := c.Mailer.SendMail(ctx, message); err != nil {
+			return nil, fmt.Errorf("unable to send confirmation email: %w", err)
+		}
+	}
+
+	return &pending, nil
+}
+
+// ConfirmEmailChange confirms one address of a pending email change with the token that was
+// emailed to it. When this completes the confirmation of both addresses, the user's email is
+// changed and IsEmailVerified is set, since the user has just proven they own the new address.
+// The returned change reports which addresses are confirmed so far. A SingleUse token is
+// redeemed as it is accepted.
+func (c *Client) ConfirmEmailChange(ctx context.Context, plaintext string) (*PendingEmailChange, error) {
+	if plaintext == "" {
+		return nil, ErrInvalidEmailChangeToken
+	}
+
+	// Look up the token
+	token, err := c.GetTokenByPlaintext(GetTokenByPlaintextInput{Plaintext: plaintext})
+	if err != nil {
+		return nil, ErrInvalidEmailChangeToken
+	}
+	if token.Scope != ScopeEmailChange || token.UserID == uuid.Nil || !time.Now().Before(token.Expiry) {
+		return nil, ErrInvalidEmailChangeToken
+	}
+	if err := c.redeemToken(token); err != nil {
+		return nil, ErrInvalidEmailChangeToken
+	}
+
+	// Mark the matching address as confirmed
+	var pending PendingEmailChange
+	err = c.doJSON(ctx, http.MethodPost, []string{"users", token.UserID.String(), "email-change", "confirm"}, map[string]string{"token_hash": hex.EncodeToString(token.Hash)}, http.StatusOK, &pending, ErrNoPendingEmailChange)
+	if errors.Is(err, ErrNoPendingEmailChange) {
+		return nil, ErrInvalidEmailChangeToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !pending.Confirmed() {
+		return &pending, nil
+	}
+
+	// Both addresses are confirmed, so apply the change unless the account moved on meanwhile
+	user, err := c.GetUserByID(token.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch user: %w", err)
+	}
+	if !strings.EqualFold(user.Email, pending.CurrentEmail) {
+		if err := c.CancelEmailChange(ctx, token.UserID); err != nil && !errors.Is(err, ErrNoPendingEmailChange) {
+			return nil, err
+		}
+		return nil, errors.New("email was changed by another request; the pending change was cancelled")
+	}
+
+	verified := true
+	if err := c.UpdateUser(token.UserID, &UpdateUserPayload{Email: &pending.NewEmail, IsEmailVerified: &verified}); err != nil {
+		return nil, fmt.Errorf("unable to update email: %w", err)
+	}
+
+	if err := c.CancelEmailChange(ctx, token.UserID); err != nil && !errors.Is(err, ErrNoPendingEmailChange) {
+		return nil, fmt.Errorf("email was changed but the pending change could not be cleared: %w", err)
+	}
+
+	return &pending, nil
+}
+
+// GetPendingEmailChange returns the email change a user has in progress, or ErrNoPendingEmailChange.
+func (c *Client) GetPendingEmailChange(ctx context.Context, userID uuid.UUID) (*PendingEmailChange, error) {
+	if userID == uuid.Nil {
+		return nil, errors.New("user ID is required")
+	}
+
+	var pending PendingEmailChange
+	if err := c.doJSON(ctx, http.MethodGet, []string{"users", userID.String(), "email-change"}, nil, http.StatusOK, &pending, ErrNoPendingEmailChange); err != nil {
+		return nil, err
+	}
+	return &pending, nil
+}
+
+// CancelEmailChange discards a user's pending email change. The tokens that were emailed for it
+// can no longer confirm anything.
+func (c *Client) CancelEmailChange(ctx context.Context, userID uuid.UUID) error {
+	if userID == uuid.Nil {
+		return errors.New("user ID is required")
+	}
+
+	return c.doJSON(ctx, http.MethodDelete, []string{"users", userID.String(), "email-change"}, nil, http.StatusNoContent, nil, ErrNoPendingEmailChange)
+}
+
+// emailChangeMailData returns the template data of an email change confirmation email.
+func emailChangeMailData(token *Token, newEmail string) map[string]string {
+	return map[string]string{
+		"token":      token.Plaintext,
+		"new_email":  newEmail,
+		"expires_at": token.Expiry.Format(time.RFC3339),
+	}
+}
@@ -16,10 +16,11 @@ import (
 
 // Enterprise represents the structure of an enterprise.
 type Enterprise struct {
-	ID            uuid.UUID `json:"id"`
-	UserAccountID uuid.UUID `json:"user_account_id"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	ID            uuid.UUID     `json:"id"`
+	UserAccountID uuid.UUID     `json:"user_account_id"`
+	Status        AccountStatus `json:"status,omitempty"`
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
 }
 
 type CreateEnterpriseAccountInput struct {
@@ -259,7 +260,8 @@ type EnterpriseAccountsResponse struct {
 	EnterpriseAccounts []*Enterprise `json:"enterprise_accounts"`
 }
 
-func (c *Client) ListEnterpriseAccounts() ([]*Enterprise, error) {
+// ListEnterpriseAccounts lists enterprise accounts, optionally restricted to the given statuses.
+func (c *Client) ListEnterpriseAccounts(statuses ...AccountStatus) ([]*Enterprise, error) {
 	// Prepare request
 	reqURL, err := url.Parse(c.BaseURL)
 	if err != nil {
@@ -267,6 +269,7 @@ func (c *Client) ListEnterpriseAccounts() ([]*Enterprise, error) {
 	}
 
 	reqURL.Path = path.Join(reqURL.Path, "/enterprise") // replace with actual API endpoint path
+	reqURL.RawQuery = accountStatusQuery(statuses)
 	req, err := http.NewRequest("GET", reqURL.String(), nil)
 	if err != nil {
 		return nil, err
@@ -302,10 +305,23 @@ func (c *Client) ListEnterpriseAccounts() ([]*Enterprise, error) {
 		return nil, err
 	}
 
-	return enterpriseAccountsResp.EnterpriseAccounts, nil
+	// Filter by status in case the server ignored the query
+	filtered := enterpriseAccountsResp.EnterpriseAccounts[:0]
+	for _, enterprise := range enterpriseAccountsResp.EnterpriseAccounts {
+		if matchesAccountStatus(enterprise.Status, statuses) {
+			filtered = append(filtered, enterprise)
+		}
+	}
+
+	return filtered, nil
 }
 
 func (c *Client) AddMemberToEnterpriseAccount(input AddMemberToEnterpriseAccountInput) error {
+	// Check the role may be given to the member
+	if err := c.enforceCompanyDomainByRoleID(input.UserID, input.RoleID, "enterprise", input.EnterpriseID); err != nil {
+		return err
+	}
+
 	// Create the URL for the API endpoint
 	u, err := url.Parse(c.BaseURL)
 	if err != nil {
@@ -433,6 +449,11 @@ func (c *Client) GetMembersOfEnterpriseAccount(enterpriseID uuid.UUID) (*Enterpr
 }
 
 func (c *Client) UpdateMemberRoleInEnterpriseAccount(req UpdateMemberRoleInEnterpriseAccountRequest) error {
+	// Check the role may be given to the member
+	if err := c.enforceCompanyDomainByRoleID(req.UserID, req.NewRoleID, "enterprise", req.EnterpriseID); err != nil {
+		return err
+	}
+
 	url, err := url.Parse(c.BaseURL)
 	if err != nil {
 		return err
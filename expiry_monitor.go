@@ -0,0 +1,351 @@
+package accountslib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ExpiryKind names the kind of credential an ExpiryNotice is about.
+type ExpiryKind string
+
+// Kinds of credential watched by an ExpiryMonitor.
+const (
+	ExpiryKindServiceAccount ExpiryKind = "service_account"
+	ExpiryKindToken          ExpiryKind = "token"
+)
+
+// ExpiryNotice warns that a credential is about to expire, or has expired.
+type ExpiryNotice struct {
+	Kind ExpiryKind
+	ID   uuid.UUID
+	// Name is the service name of a service account or the scope of a token.
+	Name string
+	// OwnerID is the user a token belongs to. It is nil for service accounts.
+	OwnerID   uuid.UUID
+	ExpiresAt time.Time
+	// LeadTime is the lead time whose threshold was crossed, or zero once the credential has expired.
+	LeadTime time.Duration
+	// Remaining is the time left before expiry when the notice was raised; negative once expired.
+	Remaining time.Duration
+}
+
+// Expired reports whether the credential had already expired when the notice was raised.
+func (n ExpiryNotice) Expired() bool {
+	return n.Remaining <= 0
+}
+
+// key identifies the notice for deduplication. Extending the expiry produces new keys, so the
+// warnings fire again for the new date.
+func (n ExpiryNotice) key() string {
+	return fmt.Sprintf("%s/%s/%d/%d", n.Kind, n.ID, n.ExpiresAt.Unix(), n.LeadTime)
+}
+
+// ExpiryNotifier delivers expiry notices, for example to chat, email or a pager.
+type ExpiryNotifier interface {
+	NotifyExpiry(ctx context.Context, notice ExpiryNotice) error
+}
+
+// ExpiryNotifierFunc adapts a function to ExpiryNotifier.
+type ExpiryNotifierFunc func(ctx context.Context, notice ExpiryNotice) error
+
+// NotifyExpiry calls f.
+func (f ExpiryNotifierFunc) NotifyExpiry(ctx context.Context, notice ExpiryNotice) error {
+	return f(ctx, notice)
+}
+
+// ExpiryNoticeLog remembers which notices were delivered, so that each fires once. Back it with
+// shared storage when several replicas run a monitor or when notices must not repeat after a
+// restart.
+type ExpiryNoticeLog interface {
+	Delivered(ctx context.Context, key string) (bool, error)
+	MarkDelivered(ctx context.Context, key string) error
+}
+
+// ExpiryNoticePruner is implemented by an ExpiryNoticeLog that can forget old notices. After each
+// scan an ExpiryMonitor prunes the notices of credentials that expired before the given time,
+// which it no longer scans.
+type ExpiryNoticePruner interface {
+	Prune(ctx context.Context, expiredBefore time.Time) error
+}
+
+// MemoryExpiryNoticeLog is an ExpiryNoticeLog held in memory. It is safe for concurrent use.
+type MemoryExpiryNoticeLog struct {
+	mu sync.Mutex
+	// keys maps each delivered notice to the expiry of its credential.
+	keys map[string]time.Time
+}
+
+// Delivered reports whether the notice with the given key was delivered.
+func (l *MemoryExpiryNoticeLog) Delivered(_ context.Context, key string) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, ok := l.keys[key]
+	return ok, nil
+}
+
+// MarkDelivered records that the notice with the given key was delivered.
+func (l *MemoryExpiryNoticeLog) MarkDelivered(_ context.Context, key string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.keys == nil {
+		l.keys = make(map[string]time.Time)
+	}
+	l.keys[key] = expiryFromNoticeKey(key)
+	return nil
+}
+
+// Prune forgets the notices of credentials that expired before expiredBefore.
+func (l *MemoryExpiryNoticeLog) Prune(_ context.Context, expiredBefore time.Time) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for key, expiresAt := range l.keys {
+		if !expiresAt.IsZero() && expiresAt.Before(expiredBefore) {
+			delete(l.keys, key)
+		}
+	}
+	return nil
+}
+
+// expiryFromNoticeKey returns the expiry recorded in a notice key, or the zero time for keys
+// not made by ExpiryNotice.key.
+func expiryFromNoticeKey(key string) time.Time {
+	parts := strings.Split(key, "/")
+	if len(parts) != 4 {
+		return time.Time{}
+	}
+	unix, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(unix, 0)
+}
+
+// ExpiryMonitorOptions configures an ExpiryMonitor. Zero fields take the defaults described on each field.
+type ExpiryMonitorOptions struct {
+	// Notifier delivers the notices. It is required unless DryRun is set.
+	Notifier ExpiryNotifier
+	// LeadTimes are how long before expiry warnings fire. Defaults to 30, 7 and 1 days. Credentials
+	// that expired longer ago than the largest lead time are no longer scanned.
+	LeadTimes []time.Duration
+	// TokenScopes lists the scopes whose tokens are watched as well as service accounts.
+	TokenScopes []TokenScope
+	// DryRun reports the notices that are due without delivering or recording them. Notices
+	// already in Log are still left out, so a dry run shows what the next real scan would send.
+	DryRun bool
+	// Log defaults to a MemoryExpiryNoticeLog.
+	Log ExpiryNoticeLog
+	// Interval is the time between scans made by Run. Defaults to an hour.
+	Interval time.Duration
+	// Clock defaults to SystemClock.
+	Clock Clock
+}
+
+func (o ExpiryMonitorOptions) withDefaults() ExpiryMonitorOptions {
+	if len(o.LeadTimes) == 0 {
+		o.LeadTimes = []time.Duration{30 * 24 * time.Hour, 7 * 24 * time.Hour, 24 * time.Hour}
+	}
+	o.LeadTimes = append([]time.Duration(nil), o.LeadTimes...)
+	sort.Slice(o.LeadTimes, func(i, j int) bool {
+		return o.LeadTimes[i] < o.LeadTimes[j]
+	})
+	if o.Log == nil {
+		o.Log = &MemoryExpiryNoticeLog{}
+	}
+	if o.Interval <= 0 {
+		o.Interval = time.Hour
+	}
+	if o.Clock == nil {
+		o.Clock = SystemClock
+	}
+	return o
+}
+
+// ExpiryReport is the outcome of one scan of an ExpiryMonitor.
+type ExpiryReport struct {
+	ScannedAt time.Time
+	// Due lists the notices raised by the scan, soonest expiry first. In dry-run mode nothing
+	// was delivered.
+	Due []ExpiryNotice
+	// Delivered counts the notices handed to the notifier without error.
+	Delivered int
+	// Failed lists the notices the notifier could not deliver; they are retried on the next scan.
+	Failed []ExpiryNotice
+}
+
+// ExpiryMonitor warns ahead of service account and token expiry. It is safe for concurrent use.
+type ExpiryMonitor struct {
+	client  *Client
+	options ExpiryMonitorOptions
+}
+
+// NewExpiryMonitor returns a monitor for the client's service accounts and, when configured, tokens.
+func (c *Client) NewExpiryMonitor(opts ...ExpiryMonitorOptions) (*ExpiryMonitor, error) {
+	var options ExpiryMonitorOptions
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+	options = options.withDefaults()
+
+	if options.Notifier == nil && !options.DryRun {
+		return nil, errors.New("a notifier is required unless DryRun is set")
+	}
+	for _, leadTime := range options.LeadTimes {
+		if leadTime <= 0 {
+			return nil, errors.New("lead times must be positive")
+		}
+	}
+
+	return &ExpiryMonitor{client: c, options: options}, nil
+}
+
+// Run scans every interval until ctx is cancelled. It blocks, so it is usually started in its own goroutine.
+func (m *ExpiryMonitor) Run(ctx context.Context) {
+	for {
+		if _, err := m.Check(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("expiry monitor: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-m.options.Clock.After(m.options.Interval):
+		}
+	}
+}
+
+// Check scans once and delivers the notices that are due. Each credential gets one notice per
+// lead time it crosses, and one when it has expired; when a scan finds several thresholds
+// crossed at once, only the most urgent notice is delivered.
+func (m *ExpiryMonitor) Check(ctx context.Context) (*ExpiryReport, error) {
+	now := m.options.Clock.Now()
+	report := &ExpiryReport{ScannedAt: now}
+
+	// Credentials that expired long ago were reported then, or before the monitor was set up
+	cutoff := now.Add(-m.options.LeadTimes[len(m.options.LeadTimes)-1])
+	credentials, err := m.credentials(cutoff)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, credential := range credentials {
+		notice, keys, ok := m.notice(credential, now)
+		if !ok {
+			continue
+		}
+
+		delivered, err := m.options.Log.Delivered(ctx, notice.key())
+		if err != nil {
+			return report, fmt.Errorf("unable to read notice log: %w", err)
+		}
+		if delivered {
+			continue
+		}
+		report.Due = append(report.Due, notice)
+		if m.options.DryRun {
+			continue
+		}
+
+		if err := m.options.Notifier.NotifyExpiry(ctx, notice); err != nil {
+			log.Printf("expiry monitor: failed to deliver notice for %s %s: %v", notice.Kind, notice.ID, err)
+			report.Failed = append(report.Failed, notice)
+			continue
+		}
+		report.Delivered++
+
+		// Mark the less urgent thresholds crossed too, so they do not fire after this one
+		for _, key := range keys {
+			if err := m.options.Log.MarkDelivered(ctx, key); err != nil {
+				return report, fmt.Errorf("unable to write notice log: %w", err)
+			}
+		}
+	}
+
+	sort.Slice(report.Due, func(i, j int) bool {
+		return report.Due[i].ExpiresAt.Before(report.Due[j].ExpiresAt)
+	})
+
+	// Forget notices of credentials that are no longer scanned
+	if pruner, ok := m.options.Log.(ExpiryNoticePruner); ok && !m.options.DryRun {
+		if err := pruner.Prune(ctx, cutoff); err != nil {
+			return report, fmt.Errorf("unable to prune notice log: %w", err)
+		}
+	}
+
+	return report, nil
+}
+
+// notice returns the most urgent notice due for a credential, with the keys of every threshold
+// it has crossed.
+func (m *ExpiryMonitor) notice(credential ExpiryNotice, now time.Time) (ExpiryNotice, []string, bool) {
+	credential.Remaining = credential.ExpiresAt.Sub(now)
+
+	// Thresholds from the most to the least urgent: expiry, then the lead times in ascending order
+	thresholds := append([]time.Duration{0}, m.options.LeadTimes...)
+
+	var keys []string
+	notice, ok := credential, false
+	for _, leadTime := range thresholds {
+		if credential.Remaining > leadTime {
+			continue
+		}
+		crossed := credential
+		crossed.LeadTime = leadTime
+		keys = append(keys, crossed.key())
+		if !ok {
+			notice, ok = crossed, true
+		}
+	}
+
+	return notice, keys, ok
+}
+
+// credentials lists everything that expires at or after cutoff, as notices without a lead time.
+func (m *ExpiryMonitor) credentials(cutoff time.Time) ([]ExpiryNotice, error) {
+	var credentials []ExpiryNotice
+
+	serviceAccounts, err := m.client.ListServiceAccounts()
+	if err != nil {
+		return nil, fmt.Errorf("unable to list service accounts: %w", err)
+	}
+	for _, serviceAccount := range serviceAccounts {
+		if serviceAccount.ExpiresAt == nil || serviceAccount.ExpiresAt.Before(cutoff) {
+			continue
+		}
+		credentials = append(credentials, ExpiryNotice{
+			Kind:      ExpiryKindServiceAccount,
+			ID:        serviceAccount.ID,
+			Name:      serviceAccount.ServiceName,
+			ExpiresAt: *serviceAccount.ExpiresAt,
+		})
+	}
+
+	for _, scope := range m.options.TokenScopes {
+		tokens, err := m.client.GetTokensByScope(GetTokensByScopeInput{Scope: scope})
+		if err != nil {
+			return nil, fmt.Errorf("unable to list %s tokens: %w", scope, err)
+		}
+		for _, token := range tokens {
+			if token.Expiry.Before(cutoff) {
+				continue
+			}
+			credentials = append(credentials, ExpiryNotice{
+				Kind:      ExpiryKindToken,
+				ID:        token.ID,
+				Name:      string(token.Scope),
+				OwnerID:   token.UserID,
+				ExpiresAt: token.Expiry,
+			})
+		}
+	}
+
+	return credentials, nil
+}
@@ -16,10 +16,13 @@ import (
 
 // Government represents the structure of a government agency.
 type Government struct {
-	ID            uuid.UUID `json:"id"`
-	UserAccountID uuid.UUID `json:"user_account_id"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	ID            uuid.UUID     `json:"id"`
+	UserAccountID uuid.UUID     `json:"user_account_id"`
+	Status        AccountStatus `json:"status,omitempty"`
+	Verified      bool          `json:"verified"`
+	VerifiedAt    *time.Time    `json:"verified_at,omitempty"`
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
 }
 
 type CreateGovernmentAccountInput struct {
@@ -293,13 +296,15 @@ func (c *Client) DeleteGovernmentAccount(accountID uuid.UUID) error {
 	return nil
 }
 
-func (c *Client) ListGovernmentAccounts() ([]Government, error) {
+// ListGovernmentAccounts lists government accounts, optionally restricted to the given statuses.
+func (c *Client) ListGovernmentAccounts(statuses ...AccountStatus) ([]Government, error) {
 	// Create the request URL from BaseURL
 	requestURL, err := url.Parse(c.BaseURL)
 	if err != nil {
 		return nil, err
 	}
 	requestURL.Path = path.Join(requestURL.Path, "/api/government_accounts")
+	requestURL.RawQuery = accountStatusQuery(statuses)
 
 	// Create new HTTP request
 	req, err := http.NewRequest(http.MethodGet, requestURL.String(), nil)
@@ -331,7 +336,15 @@ func (c *Client) ListGovernmentAccounts() ([]Government, error) {
 		return nil, err
 	}
 
-	return governmentAccounts, nil
+	// Filter by status in case the server ignored the query
+	filtered := governmentAccounts[:0]
+	for _, government := range governmentAccounts {
+		if matchesAccountStatus(government.Status, statuses) {
+			filtered = append(filtered, government)
+		}
+	}
+
+	return filtered, nil
 }
 
 // Validate checks if the UpdateGovernmentAccountEvent is valid
@@ -350,6 +363,11 @@ func (input *AddMemberToGovernmentAccountInput) Validate() error {
 }
 
 func (c *Client) AddMemberToGovernmentAccount(input AddMemberToGovernmentAccountInput) error {
+	// Check the role may be given to the member
+	if err := c.enforceCompanyDomainByRoleID(input.UserID, input.RoleID, "government", input.GovernmentID); err != nil {
+		return err
+	}
+
 	// Marshal the request body to JSON
 	jsonReqBody, err := json.Marshal(input)
 	if err != nil {
@@ -486,6 +504,11 @@ func (c *Client) UpdateMemberRoleInGovernmentAccount(event UpdateMemberRoleInGov
 		return err
 	}
 
+	// Check the role may be given to the member
+	if err := c.enforceCompanyDomainByRoleID(event.UserID, event.NewRoleID, "government", event.GovernmentID); err != nil {
+		return err
+	}
+
 	// Build request URL from base URL.
 	u, err := url.Parse(c.BaseURL)
 	if err != nil {
@@ -0,0 +1,227 @@
+package accountslib
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// Token type hints from RFC 7009, also used as token_type in introspection responses.
+const (
+	TokenTypeHintAccessToken  = "access_token"
+	TokenTypeHintRefreshToken = "refresh_token"
+)
+
+// errTokenNotFound is reported when the API does not know a token.
+var errTokenNotFound = errors.New("token not found")
+
+// IntrospectionResponse is an RFC 7662 token introspection response. Only Active is set for
+// tokens that are unknown, expired or revoked.
+type IntrospectionResponse struct {
+	Active bool   `json:"active"`
+	Scope  string `json:"scope,omitempty"`
+	// ClientID is the audience the token was issued for.
+	ClientID  string `json:"client_id,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+	Aud       string `json:"aud,omitempty"`
+}
+
+// Introspect reports whether a token is active, and if so what it grants, as defined by RFC 7662.
+// Access tokens and refresh tokens are both recognised, and so are JWT access tokens when the
+// client has a LocalVerifier. An error is only returned when the accounts server could not be
+// asked; an unknown token yields an inactive response.
+func (c *Client) Introspect(ctx context.Context, token string) (*IntrospectionResponse, error) {
+	inactive := &IntrospectionResponse{Active: false}
+	if token == "" {
+		return inactive, nil
+	}
+
+	// JWT access tokens
+	if c.LocalVerifier != nil && strings.Count(token, ".") == 2 {
+		claims, err := c.LocalVerifier.VerifyClaims(ctx, token)
+		switch {
+		case err == nil:
+			return introspectClaims(claims), nil
+		case errors.Is(err, ErrJWTExpired), errors.Is(err, ErrJWTClaims):
+			return inactive, nil
+		case !errors.Is(err, ErrInvalidJWT) && !errors.Is(err, ErrUnknownSigningKey):
+			return nil, err
+		}
+		// Not a JWT this verifier knows; it may still be an opaque token
+	}
+
+	// Access tokens
+	var accessToken Token
+	err := c.doJSON(ctx, http.MethodPost, []string{"tokens", "verify"}, map[string]string{"token_hash": hex.EncodeToString(HashToken(token))}, http.StatusOK, &accessToken, errTokenNotFound)
+	if err == nil {
+		if !time.Now().Before(accessToken.Expiry) {
+			return inactive, nil
+		}
+		return &IntrospectionResponse{
+			Active:    true,
+			Scope:     string(accessToken.Scope),
+			ClientID:  accessToken.Audience,
+			TokenType: TokenTypeHintAccessToken,
+			Exp:       accessToken.Expiry.Unix(),
+			Sub:       accessToken.UserID.String(),
+			Aud:       accessToken.Audience,
+		}, nil
+	}
+	if !errors.Is(err, errTokenNotFound) {
+		return nil, err
+	}
+
+	// Refresh tokens
+	var record RefreshTokenRecord
+	err = c.doJSON(ctx, http.MethodPost, []string{"refresh-tokens", "lookup"}, map[string]string{"token_hash": hashSecret(token)}, http.StatusOK, &record, ErrInvalidRefreshToken)
+	if errors.Is(err, ErrInvalidRefreshToken) {
+		return inactive, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if record.UsedAt != nil || record.RevokedAt != nil || !time.Now().Before(record.ExpiresAt) {
+		return inactive, nil
+	}
+	response := &IntrospectionResponse{
+		Active:    true,
+		Scope:     string(ScopeRefresh),
+		TokenType: TokenTypeHintRefreshToken,
+		Exp:       record.ExpiresAt.Unix(),
+		Sub:       record.UserID.String(),
+	}
+	if !record.IssuedAt.IsZero() {
+		response.Iat = record.IssuedAt.Unix()
+	}
+	return response, nil
+}
+
+// introspectClaims describes a verified JWT access token.
+func introspectClaims(claims *AccessTokenClaims) *IntrospectionResponse {
+	response := &IntrospectionResponse{
+		Active:    true,
+		Scope:     string(claims.Scope),
+		TokenType: TokenTypeHintAccessToken,
+		Exp:       claims.ExpiresAt,
+		Iat:       claims.IssuedAt,
+		Sub:       claims.Subject,
+	}
+	if len(claims.Audience) > 0 {
+		response.ClientID = claims.Audience[0]
+		response.Aud = claims.Audience[0]
+	}
+	return response
+}
+
+// Revoke revokes a token as defined by RFC 7009. hint may be TokenTypeHintAccessToken,
+// TokenTypeHintRefreshToken or empty; it only decides which kind of token is tried first.
+// Revoking a refresh token revokes its whole token family. Unknown tokens are not an error.
+func (c *Client) Revoke(ctx context.Context, token string, hint string) error {
+	if token == "" {
+		return nil
+	}
+
+	revokers := []func() error{
+		func() error { return c.revokeAccessToken(ctx, token) },
+		func() error { return c.revokeRefreshToken(ctx, token) },
+	}
+	if hint == TokenTypeHintRefreshToken {
+		revokers[0], revokers[1] = revokers[1], revokers[0]
+	}
+
+	for _, revoke := range revokers {
+		err := revoke()
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, errTokenNotFound) && !errors.Is(err, ErrInvalidRefreshToken) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// revokeAccessToken deletes an access token identified by its hash.
+func (c *Client) revokeAccessToken(ctx context.Context, token string) error {
+	return c.doJSON(ctx, http.MethodPost, []string{"tokens", "revoke"}, map[string]string{"token_hash": hex.EncodeToString(HashToken(token))}, http.StatusNoContent, nil, errTokenNotFound)
+}
+
+// revokeRefreshToken revokes the family of a refresh token.
+func (c *Client) revokeRefreshToken(ctx context.Context, token string) error {
+	var record RefreshTokenRecord
+	err := c.doJSON(ctx, http.MethodPost, []string{"refresh-tokens", "lookup"}, map[string]string{"token_hash": hashSecret(token)}, http.StatusOK, &record, ErrInvalidRefreshToken)
+	if err != nil {
+		return err
+	}
+	return c.RevokeTokenFamily(ctx, record.FamilyID)
+}
+
+// NewTokenEndpointsHandler returns an http.Handler serving RFC 7662 introspection at any path
+// ending in /introspect and RFC 7009 revocation at any path ending in /revoke, backed by the
+// client. Both endpoints are protected: authorize is called with every request and must
+// authenticate the calling service, for example with HTTP Basic credentials. A nil authorize
+// rejects every request.
+func (c *Client) NewTokenEndpointsHandler(authorize func(r *http.Request) bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Pragma", "no-cache")
+
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeOAuth2Error(w, http.StatusMethodNotAllowed, "invalid_request")
+			return
+		}
+		if authorize == nil || !authorize(r) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="token endpoints"`)
+			writeOAuth2Error(w, http.StatusUnauthorized, "invalid_client")
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			writeOAuth2Error(w, http.StatusBadRequest, "invalid_request")
+			return
+		}
+		token := r.PostForm.Get("token")
+		if token == "" {
+			writeOAuth2Error(w, http.StatusBadRequest, "invalid_request")
+			return
+		}
+
+		switch path.Base(r.URL.Path) {
+		case "introspect":
+			response, err := c.Introspect(r.Context(), token)
+			if err != nil {
+				log.Printf("token introspection failed: %v", err)
+				writeOAuth2Error(w, http.StatusServiceUnavailable, "temporarily_unavailable")
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(response)
+		case "revoke":
+			if err := c.Revoke(r.Context(), token, r.PostForm.Get("token_type_hint")); err != nil {
+				log.Printf("token revocation failed: %v", err)
+				writeOAuth2Error(w, http.StatusServiceUnavailable, "temporarily_unavailable")
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+// writeOAuth2Error writes an OAuth 2.0 error response.
+func writeOAuth2Error(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": code})
+}
@@ -0,0 +1,503 @@
+package accountslib
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidJWT is returned for tokens that are malformed, use an unsupported algorithm or
+	// fail signature verification.
+	ErrInvalidJWT = errors.New("invalid JWT")
+
+	// ErrJWTExpired is returned for tokens past their expiry or not yet valid.
+	ErrJWTExpired = errors.New("JWT has expired or is not yet valid")
+
+	// ErrJWTClaims is returned for tokens with the wrong issuer or audience, or a subject that
+	// is not a user ID.
+	ErrJWTClaims = errors.New("JWT issuer, audience or subject mismatch")
+
+	// ErrUnknownSigningKey is returned when no key in the JWKS matches the token.
+	ErrUnknownSigningKey = errors.New("unknown JWT signing key")
+)
+
+// LocalVerifierOptions configures a LocalVerifier. Zero fields take the defaults described on each field.
+type LocalVerifierOptions struct {
+	// JWKSURL is where the signing keys are published. Defaults to BaseURL + "/.well-known/jwks.json".
+	JWKSURL string
+	// Issuer, when set, must equal the token's iss claim.
+	Issuer string
+	// Audience, when set, must be one of the token's aud values.
+	Audience string
+	// Leeway allows for clock skew when checking exp, nbf and iat. Defaults to 30 seconds.
+	Leeway time.Duration
+	// RefreshInterval is how long fetched keys are trusted before they are fetched again. Defaults to an hour.
+	RefreshInterval time.Duration
+	// MinRefreshInterval limits how often a token signed by an unknown key can trigger a
+	// fetch, so forged tokens cannot flood the accounts server. Defaults to a minute.
+	MinRefreshInterval time.Duration
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+func (o LocalVerifierOptions) withDefaults(baseURL string) LocalVerifierOptions {
+	if o.JWKSURL == "" {
+		o.JWKSURL = strings.TrimSuffix(baseURL, "/") + "/.well-known/jwks.json"
+	}
+	if o.Leeway == 0 {
+		o.Leeway = 30 * time.Second
+	}
+	if o.RefreshInterval <= 0 {
+		o.RefreshInterval = time.Hour
+	}
+	if o.MinRefreshInterval <= 0 {
+		o.MinRefreshInterval = time.Minute
+	}
+	if o.Now == nil {
+		o.Now = time.Now
+	}
+	return o
+}
+
+// AccessTokenClaims are the claims of a signed access token.
+type AccessTokenClaims struct {
+	Issuer      string      `json:"iss,omitempty"`
+	Subject     string      `json:"sub"`
+	Audience    JWTAudience `json:"aud,omitempty"`
+	ExpiresAt   int64       `json:"exp"`
+	NotBefore   int64       `json:"nbf,omitempty"`
+	IssuedAt    int64       `json:"iat,omitempty"`
+	ID          string      `json:"jti,omitempty"`
+	Scope       TokenScope  `json:"scope,omitempty"`
+	Permissions []string    `json:"permissions,omitempty"`
+}
+
+// Token returns the claims in the shape VerifyToken returns. It fails with ErrJWTClaims when the
+// subject is not a user ID.
+func (c *AccessTokenClaims) Token() (*Token, error) {
+	userID, err := uuid.Parse(c.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("%w: subject %q is not a user ID", ErrJWTClaims, c.Subject)
+	}
+	token := &Token{
+		UserID: userID,
+		Expiry: time.Unix(c.ExpiresAt, 0),
+		Scope:  c.Scope,
+	}
+	if len(c.Audience) > 0 {
+		token.Audience = c.Audience[0]
+	}
+	return token, nil
+}
+
+// HasPermission reports whether the token grants permission.
+func (c *AccessTokenClaims) HasPermission(permission string) bool {
+	for _, granted := range c.Permissions {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// JWTAudience is an aud claim, which may be encoded as a single string or an array of strings.
+type JWTAudience []string
+
+func (a *JWTAudience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = JWTAudience{single}
+		return nil
+	}
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return err
+	}
+	*a = multiple
+	return nil
+}
+
+// LocalVerifier validates signed JWT access tokens without calling the accounts server for each
+// one. It checks RS256, ES256 and EdDSA signatures against keys from the server's JWKS, which
+// are cached and fetched again when they go stale or a token names a key not seen before, so
+// signing key rotation needs no restart. A LocalVerifier is safe for concurrent use.
+type LocalVerifier struct {
+	client  *Client
+	options LocalVerifierOptions
+
+	// fetchMu serialises JWKS fetches; mu guards the cached keys and fetch times.
+	fetchMu   sync.Mutex
+	mu        sync.RWMutex
+	keys      map[string]jsonWebKey
+	fetchedAt time.Time
+	// attemptedAt is when the JWKS was last requested, whether or not the fetch succeeded.
+	attemptedAt time.Time
+}
+
+// NewLocalVerifier returns a verifier that fetches signing keys through the client.
+func (c *Client) NewLocalVerifier(opts ...LocalVerifierOptions) *LocalVerifier {
+	var options LocalVerifierOptions
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+	return &LocalVerifier{client: c, options: options.withDefaults(c.BaseURL)}
+}
+
+// Verify validates a token and returns it in the same shape as VerifyToken.
+func (v *LocalVerifier) Verify(ctx context.Context, token string) (*Token, error) {
+	claims, err := v.VerifyClaims(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	return claims.Token()
+}
+
+// CheckUserAuthorization is the local counterpart of Client.CheckUserAuthorization. It reports
+// whether a valid token carries permission in its permissions claim.
+func (v *LocalVerifier) CheckUserAuthorization(ctx context.Context, token, permission string) (bool, error) {
+	claims, err := v.VerifyClaims(ctx, token)
+	if err != nil {
+		return false, err
+	}
+	return claims.HasPermission(permission), nil
+}
+
+// VerifyClaims validates a token's signature, expiry, issuer and audience and returns its claims.
+func (v *LocalVerifier) VerifyClaims(ctx context.Context, token string) (*AccessTokenClaims, error) {
+	// Split and decode the token
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: token must have three parts", ErrInvalidJWT)
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJWTSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrInvalidJWT, err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature: %v", ErrInvalidJWT, err)
+	}
+
+	// Check the signature
+	key, err := v.signingKey(ctx, header.Kid, header.Alg)
+	if err != nil {
+		return nil, err
+	}
+	if err := key.verify(header.Alg, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, err
+	}
+
+	// Check the claims
+	var claims AccessTokenClaims
+	if err := decodeJWTSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: claims: %v", ErrInvalidJWT, err)
+	}
+	if err := v.checkClaims(&claims); err != nil {
+		return nil, err
+	}
+
+	return &claims, nil
+}
+
+// checkClaims checks the time based claims, the subject, the issuer and the audience.
+func (v *LocalVerifier) checkClaims(claims *AccessTokenClaims) error {
+	now := v.options.Now()
+	leeway := v.options.Leeway
+
+	if claims.ExpiresAt == 0 || !now.Before(time.Unix(claims.ExpiresAt, 0).Add(leeway)) {
+		return ErrJWTExpired
+	}
+	if claims.NotBefore != 0 && now.Add(leeway).Before(time.Unix(claims.NotBefore, 0)) {
+		return ErrJWTExpired
+	}
+	if claims.IssuedAt != 0 && now.Add(leeway).Before(time.Unix(claims.IssuedAt, 0)) {
+		return fmt.Errorf("%w: issued in the future", ErrInvalidJWT)
+	}
+
+	if _, err := uuid.Parse(claims.Subject); err != nil {
+		return fmt.Errorf("%w: subject %q is not a user ID", ErrJWTClaims, claims.Subject)
+	}
+	if v.options.Issuer != "" && claims.Issuer != v.options.Issuer {
+		return fmt.Errorf("%w: unexpected issuer %q", ErrJWTClaims, claims.Issuer)
+	}
+	if v.options.Audience != "" {
+		found := false
+		for _, audience := range claims.Audience {
+			if audience == v.options.Audience {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%w: token is not for audience %q", ErrJWTClaims, v.options.Audience)
+		}
+	}
+
+	return nil
+}
+
+// signingKey returns the cached key a token names, refreshing the JWKS when it is stale or
+// does not contain the key.
+func (v *LocalVerifier) signingKey(ctx context.Context, kid, alg string) (*jsonWebKey, error) {
+	if key, fresh := v.cachedKey(kid, alg); key != nil && fresh {
+		return key, nil
+	}
+
+	// Refresh, unless another caller just did
+	v.fetchMu.Lock()
+	key, fresh := v.cachedKey(kid, alg)
+	v.mu.RLock()
+	sinceFetch := v.options.Now().Sub(v.attemptedAt)
+	v.mu.RUnlock()
+	if (key == nil || !fresh) && sinceFetch >= v.options.MinRefreshInterval {
+		if err := v.refresh(ctx); err != nil && key == nil {
+			v.fetchMu.Unlock()
+			return nil, err
+		}
+		key, _ = v.cachedKey(kid, alg)
+	}
+	v.fetchMu.Unlock()
+
+	if key == nil {
+		return nil, fmt.Errorf("%w: kid %q", ErrUnknownSigningKey, kid)
+	}
+	return key, nil
+}
+
+// cachedKey looks a key up in the cache and reports whether the cache is fresh. A token without
+// a kid matches the only cached key usable with its algorithm.
+func (v *LocalVerifier) cachedKey(kid, alg string) (*jsonWebKey, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	fresh := v.options.Now().Sub(v.fetchedAt) < v.options.RefreshInterval
+	if kid != "" {
+		if key, ok := v.keys[kid]; ok {
+			return &key, fresh
+		}
+		return nil, fresh
+	}
+
+	var match *jsonWebKey
+	for _, key := range v.keys {
+		if key.supports(alg) {
+			if match != nil {
+				return nil, fresh
+			}
+			key := key
+			match = &key
+		}
+	}
+	return match, fresh
+}
+
+// refresh fetches the JWKS and replaces the cached keys. Failed fetches count towards
+// MinRefreshInterval too, so an unreachable server is not asked again for every token.
+func (v *LocalVerifier) refresh(ctx context.Context) error {
+	v.mu.Lock()
+	v.attemptedAt = v.options.Now()
+	v.mu.Unlock()
+
+	// Create a new HTTP request
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.options.JWKSURL, nil)
+	if err != nil {
+		return fmt.Errorf("unable to create new request: %w", err)
+	}
+
+	// Set the appropriate headers
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", v.client.ApiKey)
+
+	// Send the HTTP request
+	res, err := v.client.HttpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to send request: %w", err)
+	}
+	defer res.Body.Close()
+
+	// Check the status code
+	if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("unexpected status code: got %v, body: %s", res.StatusCode, body)
+	}
+
+	// Decode the response body
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&set); err != nil {
+		return fmt.Errorf("unable to decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]jsonWebKey, len(set.Keys))
+	for _, key := range set.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+		if err := key.parse(); err != nil {
+			// Skip keys this verifier cannot use rather than rejecting the whole set
+			continue
+		}
+		keys[key.Kid] = key
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.keys = keys
+	v.fetchedAt = v.options.Now()
+	return nil
+}
+
+// minRSAKeyBits is the smallest RSA modulus accepted from a JWKS.
+const minRSAKeyBits = 2048
+
+// jsonWebKey is a public key from a JWKS.
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+
+	public crypto.PublicKey
+}
+
+// parse decodes the key material of a JWK.
+func (k *jsonWebKey) parse() error {
+	switch {
+	case k.Kty == "RSA":
+		n, err := decodeJWKInt(k.N)
+		if err != nil {
+			return err
+		}
+		e, err := decodeJWKInt(k.E)
+		if err != nil {
+			return err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return errors.New("RSA exponent too large")
+		}
+		if n.BitLen() < minRSAKeyBits {
+			return fmt.Errorf("RSA key of %d bits is shorter than %d", n.BitLen(), minRSAKeyBits)
+		}
+		k.public = &rsa.PublicKey{N: n, E: int(e.Int64())}
+	case k.Kty == "EC" && k.Crv == "P-256":
+		x, err := decodeJWKInt(k.X)
+		if err != nil {
+			return err
+		}
+		y, err := decodeJWKInt(k.Y)
+		if err != nil {
+			return err
+		}
+		if !elliptic.P256().IsOnCurve(x, y) {
+			return errors.New("EC point is not on P-256")
+		}
+		k.public = &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+	case k.Kty == "OKP" && k.Crv == "Ed25519":
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return errors.New("invalid Ed25519 key size")
+		}
+		k.public = ed25519.PublicKey(x)
+	default:
+		return fmt.Errorf("unsupported key type %s %s", k.Kty, k.Crv)
+	}
+	return nil
+}
+
+// supports reports whether the key can verify signatures made with alg.
+func (k *jsonWebKey) supports(alg string) bool {
+	if k.Alg != "" && k.Alg != alg {
+		return false
+	}
+	switch k.public.(type) {
+	case *rsa.PublicKey:
+		return alg == "RS256"
+	case *ecdsa.PublicKey:
+		return alg == "ES256"
+	case ed25519.PublicKey:
+		return alg == "EdDSA"
+	}
+	return false
+}
+
+// verify checks signature over signed. The algorithm must match the key, so a token cannot pick
+// a weaker algorithm than the key was published for.
+func (k *jsonWebKey) verify(alg string, signed, signature []byte) error {
+	if !k.supports(alg) {
+		return fmt.Errorf("%w: algorithm %q does not match key %q", ErrInvalidJWT, alg, k.Kid)
+	}
+
+	switch public := k.public.(type) {
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(signed)
+		if err := rsa.VerifyPKCS1v15(public, crypto.SHA256, digest[:], signature); err != nil {
+			return fmt.Errorf("%w: bad signature", ErrInvalidJWT)
+		}
+	case *ecdsa.PublicKey:
+		if len(signature) != 64 {
+			return fmt.Errorf("%w: bad signature length", ErrInvalidJWT)
+		}
+		digest := sha256.Sum256(signed)
+		r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(public, digest[:], r, s) {
+			return fmt.Errorf("%w: bad signature", ErrInvalidJWT)
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(public, signed, signature) {
+			return fmt.Errorf("%w: bad signature", ErrInvalidJWT)
+		}
+	}
+	return nil
+}
+
+// decodeJWTSegment decodes a base64url encoded JSON segment of a JWT.
+func decodeJWTSegment(segment string, out interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	return decoder.Decode(out)
+}
+
+// decodeJWKInt decodes a base64url encoded big-endian integer from a JWK.
+func decodeJWKInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, errors.New("empty key parameter")
+	}
+	return new(big.Int).SetBytes(data), nil
+}
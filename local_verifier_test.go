@@ -0,0 +1,269 @@
+package accountslib
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// verifierTestNow is the time seen by the verifiers under test.
+var verifierTestNow = time.Unix(1700000000, 0)
+
+// newJWKSServer serves keys as a JWKS and counts the requests for it. Nil keys make
+// every request fail.
+func newJWKSServer(t *testing.T, keys []map[string]string) (*httptest.Server, func() int) {
+	var mu sync.Mutex
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		if keys == nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return calls
+	}
+}
+
+// ecJWK returns the public JWK of an ES256 key.
+func ecJWK(kid string, key *ecdsa.PrivateKey) map[string]string {
+	return map[string]string{
+		"kty": "EC",
+		"crv": "P-256",
+		"kid": kid,
+		"use": "sig",
+		"x":   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+		"y":   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+	}
+}
+
+// rsaJWK returns the public JWK of an RSA key.
+func rsaJWK(kid string, key *rsa.PrivateKey) map[string]string {
+	return map[string]string{
+		"kty": "RSA",
+		"kid": kid,
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+// signJWT encodes header and claims and signs them with key, which is an *ecdsa.PrivateKey
+// or an *rsa.PrivateKey.
+func signJWT(t *testing.T, header map[string]string, claims map[string]interface{}, key crypto.Signer) string {
+	t.Helper()
+	encode := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+
+	signed := encode(header) + "." + encode(claims)
+	digest := sha256.Sum256([]byte(signed))
+
+	var signature []byte
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	case *rsa.PrivateKey:
+		var err error
+		signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+	default:
+		t.Fatalf("unsupported key type %T", key)
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestLocalVerifierRejects(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID := uuid.New()
+
+	validClaims := func() map[string]interface{} {
+		return map[string]interface{}{
+			"iss":   "https://accounts.example.com",
+			"sub":   userID.String(),
+			"aud":   "billing",
+			"exp":   verifierTestNow.Add(time.Hour).Unix(),
+			"iat":   verifierTestNow.Unix(),
+			"scope": "api",
+		}
+	}
+	validHeader := func() map[string]string {
+		return map[string]string{"alg": "ES256", "kid": "ec-1", "typ": "JWT"}
+	}
+
+	tests := []struct {
+		name   string
+		header func(h map[string]string)
+		claims func(c map[string]interface{})
+		want   error
+	}{
+		{name: "valid"},
+		{name: "audience in a list", claims: func(c map[string]interface{}) { c["aud"] = []string{"reports", "billing"} }},
+		{name: "expired within leeway", claims: func(c map[string]interface{}) { c["exp"] = verifierTestNow.Add(-20 * time.Second).Unix() }},
+
+		{name: "alg none", header: func(h map[string]string) { h["alg"] = "none" }, want: ErrInvalidJWT},
+		{name: "alg HS256", header: func(h map[string]string) { h["alg"] = "HS256" }, want: ErrInvalidJWT},
+		{name: "alg of another key type", header: func(h map[string]string) { h["alg"] = "RS256" }, want: ErrInvalidJWT},
+		{name: "unknown kid", header: func(h map[string]string) { h["kid"] = "ec-2" }, want: ErrUnknownSigningKey},
+		{name: "expired", claims: func(c map[string]interface{}) { c["exp"] = verifierTestNow.Add(-time.Minute).Unix() }, want: ErrJWTExpired},
+		{name: "no exp", claims: func(c map[string]interface{}) { delete(c, "exp") }, want: ErrJWTExpired},
+		{name: "not yet valid", claims: func(c map[string]interface{}) { c["nbf"] = verifierTestNow.Add(time.Minute).Unix() }, want: ErrJWTExpired},
+		{name: "wrong audience", claims: func(c map[string]interface{}) { c["aud"] = "reports" }, want: ErrJWTClaims},
+		{name: "no audience", claims: func(c map[string]interface{}) { delete(c, "aud") }, want: ErrJWTClaims},
+		{name: "wrong issuer", claims: func(c map[string]interface{}) { c["iss"] = "https://evil.example.com" }, want: ErrJWTClaims},
+		{name: "subject not a user ID", claims: func(c map[string]interface{}) { c["sub"] = "service" }, want: ErrJWTClaims},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := newJWKSServer(t, []map[string]string{ecJWK("ec-1", ecKey)})
+			verifier := NewClient(server.URL, "token", "key").NewLocalVerifier(LocalVerifierOptions{
+				Issuer:   "https://accounts.example.com",
+				Audience: "billing",
+				Now:      func() time.Time { return verifierTestNow },
+			})
+
+			header, claims := validHeader(), validClaims()
+			if tt.header != nil {
+				tt.header(header)
+			}
+			if tt.claims != nil {
+				tt.claims(claims)
+			}
+
+			token, err := verifier.Verify(context.Background(), signJWT(t, header, claims, ecKey))
+			if tt.want != nil {
+				if !errors.Is(err, tt.want) {
+					t.Errorf("Verify returned %v, want %v", err, tt.want)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify returned error: %v", err)
+			}
+			if token.UserID != userID || token.Scope != ScopeAPI {
+				t.Errorf("unexpected token: %+v", token)
+			}
+		})
+	}
+}
+
+func TestLocalVerifierRejectsTamperedClaims(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, _ := newJWKSServer(t, []map[string]string{ecJWK("ec-1", ecKey)})
+	verifier := NewClient(server.URL, "token", "key").NewLocalVerifier(LocalVerifierOptions{
+		Now: func() time.Time { return verifierTestNow },
+	})
+
+	header := map[string]string{"alg": "ES256", "kid": "ec-1"}
+	claims := map[string]interface{}{"sub": uuid.New().String(), "exp": verifierTestNow.Add(time.Hour).Unix()}
+	genuine := strings.Split(signJWT(t, header, claims, ecKey), ".")
+
+	// Swap in other claims but keep the genuine signature
+	claims["scope"] = "admin"
+	tampered := strings.Split(signJWT(t, header, claims, ecKey), ".")
+	forged := strings.Join([]string{genuine[0], tampered[1], genuine[2]}, ".")
+
+	if _, err := verifier.Verify(context.Background(), forged); !errors.Is(err, ErrInvalidJWT) {
+		t.Errorf("Verify returned %v, want %v", err, ErrInvalidJWT)
+	}
+}
+
+func TestLocalVerifierRejectsShortRSAKeys(t *testing.T) {
+	shortKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, _ := newJWKSServer(t, []map[string]string{rsaJWK("rsa-short", shortKey), rsaJWK("rsa-1", key)})
+	verifier := NewClient(server.URL, "token", "key").NewLocalVerifier(LocalVerifierOptions{
+		Now: func() time.Time { return verifierTestNow },
+	})
+
+	claims := map[string]interface{}{"sub": uuid.New().String(), "exp": verifierTestNow.Add(time.Hour).Unix()}
+
+	token := signJWT(t, map[string]string{"alg": "RS256", "kid": "rsa-1"}, claims, key)
+	if _, err := verifier.Verify(context.Background(), token); err != nil {
+		t.Errorf("Verify with a 2048 bit key returned error: %v", err)
+	}
+
+	token = signJWT(t, map[string]string{"alg": "RS256", "kid": "rsa-short"}, claims, shortKey)
+	if _, err := verifier.Verify(context.Background(), token); !errors.Is(err, ErrUnknownSigningKey) {
+		t.Errorf("Verify with a 1024 bit key returned %v, want %v", err, ErrUnknownSigningKey)
+	}
+}
+
+func TestLocalVerifierLimitsFailedFetches(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, calls := newJWKSServer(t, nil)
+
+	now := verifierTestNow
+	verifier := NewClient(server.URL, "token", "key").NewLocalVerifier(LocalVerifierOptions{
+		Now: func() time.Time { return now },
+	})
+
+	claims := map[string]interface{}{"sub": uuid.New().String(), "exp": verifierTestNow.Add(time.Hour).Unix()}
+	token := signJWT(t, map[string]string{"alg": "ES256", "kid": "ec-1"}, claims, ecKey)
+
+	for i := 0; i < 3; i++ {
+		if _, err := verifier.Verify(context.Background(), token); err == nil {
+			t.Fatal("expected an error while the JWKS is unavailable")
+		}
+	}
+	if calls() != 1 {
+		t.Errorf("expected 1 JWKS fetch within MinRefreshInterval, got %d", calls())
+	}
+
+	now = now.Add(time.Minute)
+	verifier.Verify(context.Background(), token)
+	if calls() != 2 {
+		t.Errorf("expected a second JWKS fetch after MinRefreshInterval, got %d", calls())
+	}
+}
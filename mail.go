@@ -0,0 +1,23 @@
+package accountslib
+
+import "context"
+
+// Mail templates used by the flows that email users. The Mailer is expected to
+// render its own message for each template from the data it is given.
+const (
+	MailTemplatePasswordReset      = "password_reset"
+	MailTemplateEmailChangeCurrent = "email_change_current"
+	MailTemplateEmailChangeNew     = "email_change_new"
+)
+
+// MailMessage is a transactional email to a single recipient.
+type MailMessage struct {
+	To       string
+	Template string
+	Data     map[string]string
+}
+
+// Mailer delivers transactional email on behalf of the client.
+type Mailer interface {
+	SendMail(ctx context.Context, message MailMessage) error
+}
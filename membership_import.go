@@ -0,0 +1,470 @@
+package accountslib
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MembershipFormat is the serialization format used for membership import and export.
+type MembershipFormat string
+
+const (
+	// MembershipFormatCSV is a comma separated file with a header row.
+	MembershipFormatCSV MembershipFormat = "csv"
+	// MembershipFormatNDJSON is newline delimited JSON, one AccountMembership per line.
+	MembershipFormatNDJSON MembershipFormat = "ndjson"
+)
+
+// Membership import row statuses.
+const (
+	MembershipImportCreated = "created"
+	MembershipImportDryRun  = "dry_run"
+	MembershipImportSkipped = "skipped"
+	MembershipImportFailed  = "failed"
+)
+
+// membershipCSVHeader is the column order written by ExportMemberships.
+// ImportMemberships matches columns by name, so only account_type, account_id,
+// user_id and role are required on import.
+var membershipCSVHeader = []string{"id", "account_type", "account_id", "user_id", "role", "joined_at"}
+
+// ImportMembershipsOptions controls how ImportMemberships processes its input.
+type ImportMembershipsOptions struct {
+	// Concurrency is the number of rows submitted in parallel. Defaults to 4.
+	Concurrency int
+	// RequestsPerSecond caps the rate of membership creation calls. Zero means unlimited.
+	RequestsPerSecond float64
+	// DryRun validates every row and resolves its role without creating anything.
+	DryRun bool
+	// ResumeAfterRow skips every data row up to and including this row number.
+	// Pass the Checkpoint of a previous report to resume an interrupted import.
+	ResumeAfterRow int
+	// OnCheckpoint is called whenever every row up to the given row number has completed
+	// without failing. A dry run creates nothing, so it never reports a checkpoint.
+	OnCheckpoint func(row int)
+}
+
+// MembershipImportResult is the outcome of importing a single row.
+type MembershipImportResult struct {
+	Row        int               `json:"row"`
+	Membership AccountMembership `json:"membership"`
+	Status     string            `json:"status"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// MembershipImportReport summarizes an ImportMemberships run.
+type MembershipImportReport struct {
+	DryRun     bool                     `json:"dry_run"`
+	Results    []MembershipImportResult `json:"results"`
+	Created    int                      `json:"created"`
+	Validated  int                      `json:"validated"`
+	Failed     int                      `json:"failed"`
+	Skipped    int                      `json:"skipped"`
+	Checkpoint int                      `json:"checkpoint"`
+}
+
+type membershipImportRow struct {
+	row        int
+	membership AccountMembership
+	err        error
+}
+
+// ImportMemberships reads memberships from r and creates each of them through
+// CreateAccountMembership. Every row's role must resolve through GetRoleByName.
+// A failing row does not stop the import; its error is recorded in the report.
+func (c *Client) ImportMemberships(ctx context.Context, r io.Reader, format MembershipFormat, opts ...ImportMembershipsOptions) (*MembershipImportReport, error) {
+	var options ImportMembershipsOptions
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+	if options.Concurrency <= 0 {
+		options.Concurrency = 4
+	}
+
+	// Parse the whole input up front so malformed files fail before any writes
+	rows, err := readMembershipRows(r, format)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &MembershipImportReport{
+		DryRun:     options.DryRun,
+		Checkpoint: options.ResumeAfterRow,
+	}
+
+	var pending []membershipImportRow
+	for _, row := range rows {
+		if row.row <= options.ResumeAfterRow {
+			report.Results = append(report.Results, MembershipImportResult{Row: row.row, Membership: row.membership, Status: MembershipImportSkipped})
+			report.Skipped++
+			continue
+		}
+		pending = append(pending, row)
+	}
+
+	// Throttle calls to the server when a rate is configured
+	var throttle <-chan time.Time
+	if options.RequestsPerSecond > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / options.RequestsPerSecond))
+		defer ticker.Stop()
+		throttle = ticker.C
+	}
+
+	roles := &membershipRoleCache{client: c, roles: make(map[string]bool)}
+	results := make([]MembershipImportResult, len(pending))
+	tracker := newMembershipCheckpointTracker(pending, options.ResumeAfterRow, options.OnCheckpoint)
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < options.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				result, completed := c.importMembershipRow(ctx, pending[idx], roles, options.DryRun, throttle)
+				if !completed {
+					continue
+				}
+				results[idx] = result
+				// A dry run creates nothing, so there is nothing to resume from
+				if result.Status == MembershipImportCreated {
+					tracker.done(pending[idx].row)
+				}
+			}
+		}()
+	}
+
+	// Feed rows to the workers until the input is exhausted or ctx is cancelled
+	var ctxErr error
+feed:
+	for idx := range pending {
+		select {
+		case <-ctx.Done():
+			ctxErr = ctx.Err()
+			break feed
+		case jobs <- idx:
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, result := range results {
+		if result.Row == 0 {
+			// Not attempted because the context was cancelled
+			continue
+		}
+		switch result.Status {
+		case MembershipImportCreated:
+			report.Created++
+		case MembershipImportDryRun:
+			report.Validated++
+		case MembershipImportFailed:
+			report.Failed++
+		}
+		report.Results = append(report.Results, result)
+	}
+	report.Checkpoint = tracker.checkpoint()
+
+	if ctxErr != nil {
+		return report, fmt.Errorf("import interrupted after row %d: %w", report.Checkpoint, ctxErr)
+	}
+
+	return report, nil
+}
+
+// importMembershipRow submits a single row. It reports false when the row was
+// not attempted because ctx was cancelled while waiting for the rate limiter.
+func (c *Client) importMembershipRow(ctx context.Context, row membershipImportRow, roles *membershipRoleCache, dryRun bool, throttle <-chan time.Time) (MembershipImportResult, bool) {
+	result := MembershipImportResult{Row: row.row, Membership: row.membership}
+
+	fail := func(err error) (MembershipImportResult, bool) {
+		result.Status = MembershipImportFailed
+		result.Error = err.Error()
+		return result, true
+	}
+
+	if row.err != nil {
+		return fail(row.err)
+	}
+
+	if throttle != nil {
+		select {
+		case <-ctx.Done():
+			return result, false
+		case <-throttle:
+		}
+	}
+
+	// Make sure the role exists before creating anything that references it
+	if err := roles.resolve(ctx, row.membership.Role); err != nil {
+		return fail(err)
+	}
+
+	if dryRun {
+		result.Status = MembershipImportDryRun
+		return result, true
+	}
+
+	created, err := c.createAccountMembership(ctx, &row.membership)
+	if err != nil {
+		return fail(err)
+	}
+
+	result.Membership = *created
+	result.Status = MembershipImportCreated
+	return result, true
+}
+
+// ExportMemberships writes every membership of the given account to w.
+func (c *Client) ExportMemberships(ctx context.Context, accountID uuid.UUID, w io.Writer, format MembershipFormat) error {
+	if accountID == uuid.Nil {
+		return errors.New("account ID is required")
+	}
+
+	resp, err := c.GetAccountMembershipsByAccountID(accountID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch memberships: %w", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	switch format {
+	case MembershipFormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(membershipCSVHeader); err != nil {
+			return err
+		}
+		for _, m := range resp.AccountMemberships {
+			record := []string{
+				m.ID.String(),
+				m.AccountType,
+				m.AccountID.String(),
+				m.UserID.String(),
+				m.Role,
+				m.JoinedAt.Format(time.RFC3339),
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	case MembershipFormatNDJSON:
+		encoder := json.NewEncoder(w)
+		for _, m := range resp.AccountMemberships {
+			if err := encoder.Encode(m); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported membership format: %q", format)
+	}
+}
+
+// readMembershipRows decodes every row of r. Rows that fail to parse are
+// returned with their error set so they show up in the report.
+func readMembershipRows(r io.Reader, format MembershipFormat) ([]membershipImportRow, error) {
+	switch format {
+	case MembershipFormatCSV:
+		return readMembershipCSV(r)
+	case MembershipFormatNDJSON:
+		return readMembershipNDJSON(r)
+	default:
+		return nil, fmt.Errorf("unsupported membership format: %q", format)
+	}
+}
+
+func readMembershipCSV(r io.Reader) ([]membershipImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read CSV header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"account_type", "account_id", "user_id", "role"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("CSV header is missing column %q", required)
+		}
+	}
+
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var rows []membershipImportRow
+	for n := 1; ; n++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				rows = append(rows, membershipImportRow{row: n, err: err})
+				continue
+			}
+			return nil, err
+		}
+
+		row := membershipImportRow{row: n}
+		row.membership.AccountType = field(record, "account_type")
+		row.membership.Role = field(record, "role")
+		row.membership.AccountID, row.err = uuid.Parse(field(record, "account_id"))
+		if row.err != nil {
+			row.err = fmt.Errorf("invalid account_id: %w", row.err)
+		} else if row.membership.UserID, row.err = uuid.Parse(field(record, "user_id")); row.err != nil {
+			row.err = fmt.Errorf("invalid user_id: %w", row.err)
+		} else {
+			row.err = validateImportedMembership(&row.membership)
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func readMembershipNDJSON(r io.Reader) ([]membershipImportRow, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var rows []membershipImportRow
+	n := 0
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		n++
+
+		row := membershipImportRow{row: n}
+		if err := json.Unmarshal([]byte(line), &row.membership); err != nil {
+			row.err = fmt.Errorf("invalid JSON: %w", err)
+		} else {
+			row.err = validateImportedMembership(&row.membership)
+		}
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read NDJSON input: %w", err)
+	}
+
+	return rows, nil
+}
+
+func validateImportedMembership(m *AccountMembership) error {
+	// Server assigned fields are never imported
+	m.ID = uuid.Nil
+	m.JoinedAt = time.Time{}
+
+	if m.AccountType == "" {
+		return errors.New("account_type is required")
+	}
+	if m.AccountID == uuid.Nil {
+		return errors.New("account_id is required")
+	}
+	if m.UserID == uuid.Nil {
+		return errors.New("user_id is required")
+	}
+	if m.Role == "" {
+		return errors.New("role is required")
+	}
+	return nil
+}
+
+// membershipRoleCache resolves each distinct role name only once per import.
+// Failed lookups are not cached, so a transient error only fails its own row.
+type membershipRoleCache struct {
+	client *Client
+	mu     sync.Mutex
+	roles  map[string]bool
+}
+
+func (rc *membershipRoleCache) resolve(ctx context.Context, name string) error {
+	rc.mu.Lock()
+	resolved := rc.roles[name]
+	rc.mu.Unlock()
+	if resolved {
+		return nil
+	}
+
+	if _, err := rc.client.getRoleByName(ctx, name); err != nil {
+		return fmt.Errorf("unknown role %q: %w", name, err)
+	}
+
+	rc.mu.Lock()
+	rc.roles[name] = true
+	rc.mu.Unlock()
+
+	return nil
+}
+
+// membershipCheckpointTracker tracks the highest row number below which every
+// row has completed, so an interrupted import can be resumed without gaps.
+// Failed rows are never marked done, so the checkpoint stops before the first
+// of them and a resumed import retries it.
+type membershipCheckpointTracker struct {
+	mu       sync.Mutex
+	order    []int
+	finished map[int]bool
+	next     int
+	last     int
+	notify   func(row int)
+}
+
+func newMembershipCheckpointTracker(rows []membershipImportRow, start int, notify func(row int)) *membershipCheckpointTracker {
+	order := make([]int, len(rows))
+	for i, row := range rows {
+		order[i] = row.row
+	}
+	return &membershipCheckpointTracker{
+		order:    order,
+		finished: make(map[int]bool, len(rows)),
+		last:     start,
+		notify:   notify,
+	}
+}
+
+func (t *membershipCheckpointTracker) done(row int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.finished[row] = true
+	advanced := false
+	for t.next < len(t.order) && t.finished[t.order[t.next]] {
+		t.last = t.order[t.next]
+		t.next++
+		advanced = true
+	}
+	if advanced && t.notify != nil {
+		t.notify(t.last)
+	}
+}
+
+func (t *membershipCheckpointTracker) checkpoint() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.last
+}
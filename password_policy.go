@@ -0,0 +1,309 @@
+package accountslib
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Password policy violation codes.
+const (
+	PasswordTooShort         = "too_short"
+	PasswordTooLong          = "too_long"
+	PasswordMissingUppercase = "missing_uppercase"
+	PasswordMissingLowercase = "missing_lowercase"
+	PasswordMissingDigit     = "missing_digit"
+	PasswordMissingSymbol    = "missing_symbol"
+	PasswordTooManyRepeated  = "too_many_repeated"
+	PasswordContainsBanned   = "contains_banned_word"
+	PasswordBreached         = "breached"
+)
+
+// minBannedWordLength is the shortest banned word that is checked. Shorter words,
+// such as a two letter username, would reject too many good passwords.
+const minBannedWordLength = 3
+
+// PasswordPolicy describes the passwords accepted by RegisterUser, CompletePasswordReset
+// and ChangePassword. The zero value only enforces DefaultPasswordPolicy's length limits.
+type PasswordPolicy struct {
+	MinLength int
+	MaxLength int
+
+	RequireUppercase bool
+	RequireLowercase bool
+	RequireDigit     bool
+	RequireSymbol    bool
+
+	// MaxRepeated is the longest allowed run of the same character. Zero means unlimited.
+	MaxRepeated int
+
+	// BannedWords may not appear in a password, ignoring case. The user's email
+	// address, its local part and the username are always banned as well.
+	BannedWords []string
+
+	// Breached, when set, rejects passwords found in a breached password corpus.
+	Breached BreachedPasswordChecker
+}
+
+// DefaultPasswordPolicy is used when the client has no PasswordPolicy configured.
+var DefaultPasswordPolicy = PasswordPolicy{MinLength: 8, MaxLength: 255}
+
+// PasswordViolation is a single reason a password was rejected.
+type PasswordViolation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Detail  string `json:"detail,omitempty"`
+}
+
+// PasswordPolicyError lists every rule a password broke.
+type PasswordPolicyError struct {
+	Violations []PasswordViolation `json:"violations"`
+}
+
+func (e *PasswordPolicyError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, violation := range e.Violations {
+		messages[i] = violation.Message
+	}
+	return "password does not meet policy: " + strings.Join(messages, "; ")
+}
+
+// PasswordContext carries the user details a password must not contain.
+type PasswordContext struct {
+	Email    string
+	Username string
+}
+
+// Check returns every rule password breaks. It returns an error only when the breach
+// corpus could not be read.
+func (p *PasswordPolicy) Check(password string, user PasswordContext) ([]PasswordViolation, error) {
+	minLength, maxLength := p.MinLength, p.MaxLength
+	if minLength == 0 {
+		minLength = DefaultPasswordPolicy.MinLength
+	}
+	if maxLength == 0 {
+		maxLength = DefaultPasswordPolicy.MaxLength
+	}
+
+	var violations []PasswordViolation
+	add := func(code, message, detail string) {
+		violations = append(violations, PasswordViolation{Code: code, Message: message, Detail: detail})
+	}
+
+	// Length
+	length := utf8.RuneCountInString(password)
+	if length < minLength {
+		add(PasswordTooShort, fmt.Sprintf("must be at least %d characters", minLength), "")
+	}
+	if length > maxLength {
+		add(PasswordTooLong, fmt.Sprintf("must be at most %d characters", maxLength), "")
+	}
+
+	// Character classes and repeats
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	var previous rune
+	run, longestRun := 0, 0
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+
+		if r == previous {
+			run++
+		} else {
+			run = 1
+		}
+		if run > longestRun {
+			longestRun = run
+		}
+		previous = r
+	}
+	if p.RequireUppercase && !hasUpper {
+		add(PasswordMissingUppercase, "must contain an uppercase letter", "")
+	}
+	if p.RequireLowercase && !hasLower {
+		add(PasswordMissingLowercase, "must contain a lowercase letter", "")
+	}
+	if p.RequireDigit && !hasDigit {
+		add(PasswordMissingDigit, "must contain a digit", "")
+	}
+	if p.RequireSymbol && !hasSymbol {
+		add(PasswordMissingSymbol, "must contain a symbol", "")
+	}
+	if p.MaxRepeated > 0 && longestRun > p.MaxRepeated {
+		add(PasswordTooManyRepeated, fmt.Sprintf("must not repeat a character more than %d times in a row", p.MaxRepeated), "")
+	}
+
+	// Banned words, including the user's own details
+	banned := append([]string{}, p.BannedWords...)
+	if user.Email != "" {
+		banned = append(banned, user.Email)
+		if at := strings.LastIndex(user.Email, "@"); at > 0 {
+			banned = append(banned, user.Email[:at])
+		}
+	}
+	if user.Username != "" {
+		banned = append(banned, user.Username)
+	}
+	lowered := strings.ToLower(password)
+	reported := make(map[string]bool)
+	for _, word := range banned {
+		word = strings.ToLower(strings.TrimSpace(word))
+		if utf8.RuneCountInString(word) < minBannedWordLength || reported[word] {
+			continue
+		}
+		if strings.Contains(lowered, word) {
+			reported[word] = true
+			add(PasswordContainsBanned, "must not contain personal details or common words", word)
+		}
+	}
+
+	// Known breached passwords
+	if p.Breached != nil {
+		count, err := p.Breached.BreachCount(password)
+		if err != nil {
+			return violations, fmt.Errorf("unable to check breached passwords: %w", err)
+		}
+		if count > 0 {
+			add(PasswordBreached, "has appeared in a data breach", strconv.Itoa(count))
+		}
+	}
+
+	return violations, nil
+}
+
+// Validate returns a *PasswordPolicyError listing every violation, or nil if password is acceptable.
+func (p *PasswordPolicy) Validate(password string, user PasswordContext) error {
+	violations, err := p.Check(password, user)
+	if err != nil {
+		return err
+	}
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// passwordPolicy returns the client's configured policy, or DefaultPasswordPolicy.
+func (c *Client) passwordPolicy() *PasswordPolicy {
+	if c.PasswordPolicy != nil {
+		return c.PasswordPolicy
+	}
+	return &DefaultPasswordPolicy
+}
+
+// BreachedPasswordChecker reports how often a password appears in a breach corpus.
+type BreachedPasswordChecker interface {
+	BreachCount(password string) (int, error)
+}
+
+// BreachedPasswordFile checks passwords against a local copy of a k-anonymity password
+// corpus, such as the Pwned Passwords SHA-1 list ordered by hash. Each line holds an
+// upper case SHA-1 hash and a count separated by a colon, and lines are sorted by hash.
+// Lookups binary search the file, so it is never loaded into memory.
+type BreachedPasswordFile struct {
+	file *os.File
+	size int64
+}
+
+// OpenBreachedPasswordFile opens a sorted breach corpus file.
+func OpenBreachedPasswordFile(name string) (*BreachedPasswordFile, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &BreachedPasswordFile{file: file, size: info.Size()}, nil
+}
+
+// Close closes the underlying file.
+func (f *BreachedPasswordFile) Close() error {
+	return f.file.Close()
+}
+
+// BreachCount returns the number of times password appears in the corpus, or zero.
+func (f *BreachedPasswordFile) BreachCount(password string) (int, error) {
+	sum := sha1.Sum([]byte(password))
+	target := []byte(strings.ToUpper(hex.EncodeToString(sum[:])))
+
+	lo, hi := int64(0), f.size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+
+		start, line, err := f.lineAtOrAfter(mid)
+		if err != nil {
+			return 0, err
+		}
+		if start >= hi || line == nil {
+			hi = mid
+			continue
+		}
+
+		hash, count, _ := bytes.Cut(line, []byte(":"))
+		switch bytes.Compare(bytes.ToUpper(bytes.TrimSpace(hash)), target) {
+		case 0:
+			n, err := strconv.Atoi(string(bytes.TrimSpace(count)))
+			if err != nil {
+				return 0, fmt.Errorf("malformed corpus line at offset %d: %w", start, err)
+			}
+			return n, nil
+		case -1:
+			lo = start + int64(len(line)) + 1
+		default:
+			hi = mid
+		}
+	}
+
+	return 0, nil
+}
+
+// lineAtOrAfter returns the first line that starts at or after offset, without its newline.
+func (f *BreachedPasswordFile) lineAtOrAfter(offset int64) (int64, []byte, error) {
+	start := offset
+	if offset > 0 {
+		// Begin one byte early so a line starting exactly at offset is not skipped
+		start = offset - 1
+	}
+	reader := bufio.NewReader(io.NewSectionReader(f.file, start, f.size-start))
+
+	if offset > 0 {
+		// Skip the rest of the line that offset falls in
+		skipped, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return f.size, nil, nil
+		}
+		if err != nil {
+			return 0, nil, err
+		}
+		start += int64(len(skipped))
+	}
+
+	line, err := reader.ReadBytes('\n')
+	if err != nil && err != io.EOF {
+		return 0, nil, err
+	}
+	if len(line) == 0 {
+		return f.size, nil, nil
+	}
+
+	return start, bytes.TrimRight(line, "\r\n"), nil
+}
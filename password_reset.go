@@ -0,0 +1,200 @@
+package accountslib
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/google/uuid"
+)
+
+// passwordResetMinDuration is the minimum time every password reset call takes, so that
+// response times do not reveal whether an account exists or which check failed.
+const passwordResetMinDuration = 750 * time.Millisecond
+
+// ErrInvalidPasswordResetToken is returned for every unusable reset token, whether it
+// is unknown, expired, already used or issued for another scope.
+var ErrInvalidPasswordResetToken = errors.New("invalid or expired password reset token")
+
+// RequestPasswordReset emails a single-use password reset token to the user with the given
+// address. It returns nil whether or not the address belongs to an account, and always
+// takes the same time, so it cannot be used to find out which addresses are registered.
+func (c *Client) RequestPasswordReset(ctx context.Context, email string) error {
+	// Reject malformed input and missing configuration before doing anything account specific
+	if err := validation.Validate(email, validation.Required, emailRule); err != nil {
+		return fmt.Errorf("invalid email: %w", err)
+	}
+	if c.Mailer == nil {
+		return errors.New("no mailer configured")
+	}
+
+	defer padPasswordResetTiming(ctx, time.Now())
+
+	user, err := c.GetUserByEmail(email)
+	if err != nil || user == nil || !user.IsActive {
+		return nil
+	}
+
+	token, err := c.CreateToken(CreateTokenInput{UserID: user.ID, Scope: ScopePasswordReset})
+	if err != nil {
+		log.Printf("password reset: failed to create token for user %s: %v", user.ID, err)
+		return nil
+	}
+
+	err = c.Mailer.SendMail(ctx, MailMessage{
+		To:       user.Email,
+		Template: MailTemplatePasswordReset,
+		Data: map[string]string{
+			"token":      token.Plaintext,
+			"expires_at": token.Expiry.Format(time.RFC3339),
+		},
+	})
+	if err != nil {
+		log.Printf("password reset: failed to send email to user %s: %v", user.ID, err)
+	}
+
+	return nil
+}
+
+// ValidatePasswordResetToken reports whether a password reset token can still be used,
+// returning the ID of the user it belongs to. Any problem with the token yields ErrInvalidPasswordResetToken.
+func (c *Client) ValidatePasswordResetToken(ctx context.Context, plaintext string) (uuid.UUID, error) {
+	defer padPasswordResetTiming(ctx, time.Now())
+
+	token, err := c.lookupPasswordResetToken(plaintext)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return token.UserID, nil
+}
+
+// CompletePasswordReset sets a new password for the owner of a reset token. A SingleUse reset
+// token is redeemed once the password has changed, so a failed write leaves it usable. The user
+// is then signed out everywhere: every token, session and refresh token family is revoked.
+func (c *Client) CompletePasswordReset(ctx context.Context, plaintext string, newPassword string) error {
+	defer padPasswordResetTiming(ctx, time.Now())
+
+	token, err := c.lookupPasswordResetToken(plaintext)
+	if err != nil {
+		return err
+	}
+
+	// Apply the password policy
+	user, err := c.GetUserByID(token.UserID)
+	if err != nil {
+		return ErrInvalidPasswordResetToken
+	}
+	if err := c.passwordPolicy().Validate(newPassword, PasswordContext{Email: user.Email, Username: user.Username}); err != nil {
+		return err
+	}
+
+	if err := c.setUserPassword(ctx, token.UserID, newPassword); err != nil {
+		return err
+	}
+
+	if err := c.redeemToken(token); err != nil {
+		return fmt.Errorf("password was changed but the reset token could not be redeemed: %w", err)
+	}
+
+	return c.signOutEverywhere(ctx, token.UserID)
+}
+
+// signOutEverywhere revokes every token, session and refresh token family of a user after
+// their password has changed.
+func (c *Client) signOutEverywhere(ctx context.Context, userID uuid.UUID) error {
+	if err := c.DeleteTokensByUserID(DeleteTokensByUserIDInput{UserID: userID}); err != nil {
+		return fmt.Errorf("password was changed but tokens could not be revoked: %w", err)
+	}
+	if _, err := c.RevokeAllSessions(ctx, userID); err != nil {
+		return fmt.Errorf("password was changed but sessions could not be revoked: %w", err)
+	}
+	if err := c.RevokeUserTokenFamilies(ctx, userID); err != nil {
+		return fmt.Errorf("password was changed but refresh tokens could not be revoked: %w", err)
+	}
+	return nil
+}
+
+// lookupPasswordResetToken fetches a token and checks it is a live password reset token.
+func (c *Client) lookupPasswordResetToken(plaintext string) (*Token, error) {
+	if plaintext == "" {
+		return nil, ErrInvalidPasswordResetToken
+	}
+
+	token, err := c.GetTokenByPlaintext(GetTokenByPlaintextInput{Plaintext: plaintext})
+	if err != nil {
+		return nil, ErrInvalidPasswordResetToken
+	}
+	if token.Scope != ScopePasswordReset || token.UserID == uuid.Nil || !time.Now().Before(token.Expiry) {
+		return nil, ErrInvalidPasswordResetToken
+	}
+
+	return token, nil
+}
+
+// setUserPassword replaces the password of a user.
+func (c *Client) setUserPassword(ctx context.Context, userID uuid.UUID, password string) error {
+	// Marshal the payload
+	jsonPayload, err := json.Marshal(map[string]string{"password": password})
+	if err != nil {
+		return fmt.Errorf("unable to marshal payload: %w", err)
+	}
+
+	// Create the URL
+	u, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return fmt.Errorf("unable to parse base URL: %w", err)
+	}
+	u.Path = path.Join(u.Path, "api", "users", userID.String(), "password")
+
+	// Create a new HTTP request
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.String(), bytes.NewBuffer(jsonPayload))
+	if err != nil {
+		return fmt.Errorf("unable to create new request: %w", err)
+	}
+
+	// Set the appropriate headers
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	req.Header.Set("X-API-Key", c.ApiKey)
+
+	// Send the HTTP request
+	res, err := c.HttpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to send request: %w", err)
+	}
+	defer res.Body.Close()
+
+	// Check the status code
+	if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("unexpected status code: got %v, body: %s", res.StatusCode, body)
+	}
+
+	return nil
+}
+
+// padPasswordResetTiming sleeps until passwordResetMinDuration has passed since start.
+func padPasswordResetTiming(ctx context.Context, start time.Time) {
+	remaining := passwordResetMinDuration - time.Since(start)
+	if remaining <= 0 {
+		return
+	}
+
+	timer := time.NewTimer(remaining)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,140 @@
+package accountslib
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrInvalidPhoneNumber is returned when a phone number cannot be normalized to E.164.
+var ErrInvalidPhoneNumber = errors.New("invalid phone number")
+
+// e164Regex matches a normalized E.164 phone number.
+var e164Regex = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+// phoneRegion holds the dialling rules of a region needed to normalize its numbers.
+type phoneRegion struct {
+	// callingCode is the country calling code, without the plus sign.
+	callingCode string
+	// trunkPrefix is dropped from national numbers before the calling code is added.
+	trunkPrefix string
+	// internationalPrefix is dialled from the region before a calling code.
+	internationalPrefix string
+}
+
+// phoneRegions maps ISO 3166-1 alpha-2 region codes to their dialling rules.
+var phoneRegions = map[string]phoneRegion{
+	"AE": {"971", "0", "00"},
+	"AR": {"54", "0", "00"},
+	"AT": {"43", "0", "00"},
+	"AU": {"61", "0", "0011"},
+	"BD": {"880", "0", "00"},
+	"BE": {"32", "0", "00"},
+	"BR": {"55", "0", "00"},
+	"CA": {"1", "1", "011"},
+	"CH": {"41", "0", "00"},
+	"CL": {"56", "", "00"},
+	"CN": {"86", "0", "00"},
+	"CO": {"57", "", "00"},
+	"CZ": {"420", "", "00"},
+	"DE": {"49", "0", "00"},
+	"DK": {"45", "", "00"},
+	"EG": {"20", "0", "00"},
+	"ES": {"34", "", "00"},
+	"FI": {"358", "0", "00"},
+	"FR": {"33", "0", "00"},
+	"GB": {"44", "0", "00"},
+	"GR": {"30", "", "00"},
+	"HK": {"852", "", "001"},
+	"HU": {"36", "06", "00"},
+	"ID": {"62", "0", "001"},
+	"IE": {"353", "0", "00"},
+	"IL": {"972", "0", "00"},
+	"IN": {"91", "0", "00"},
+	"IT": {"39", "", "00"},
+	"JP": {"81", "0", "010"},
+	"KE": {"254", "0", "000"},
+	"KR": {"82", "0", "001"},
+	"LU": {"352", "", "00"},
+	"MX": {"52", "", "00"},
+	"MY": {"60", "0", "00"},
+	"NG": {"234", "0", "009"},
+	"NL": {"31", "0", "00"},
+	"NO": {"47", "", "00"},
+	"NZ": {"64", "0", "00"},
+	"PE": {"51", "0", "00"},
+	"PH": {"63", "0", "00"},
+	"PK": {"92", "0", "00"},
+	"PL": {"48", "", "00"},
+	"PR": {"1", "1", "011"},
+	"PT": {"351", "", "00"},
+	"RO": {"40", "0", "00"},
+	"RU": {"7", "8", "810"},
+	"SA": {"966", "0", "00"},
+	"SE": {"46", "0", "00"},
+	"SG": {"65", "", "000"},
+	"TH": {"66", "0", "001"},
+	"TR": {"90", "0", "00"},
+	"TW": {"886", "0", "002"},
+	"UA": {"380", "0", "00"},
+	"US": {"1", "1", "011"},
+	"VN": {"84", "0", "00"},
+	"ZA": {"27", "0", "00"},
+}
+
+// NormalizePhoneNumber parses a phone number as a user would type it and returns it in E.164
+// form, such as "+442079460000". Spaces, dashes, dots, slashes and parentheses are ignored.
+// Numbers that start with a plus sign are read as international. Other numbers are read in
+// the dialling plan of region, an ISO 3166-1 alpha-2 code such as "GB", which may be empty
+// only for international numbers. The check is structural: it does not tell whether a number
+// is assigned or can receive SMS.
+func NormalizePhoneNumber(number string, region string) (string, error) {
+	number = strings.TrimSpace(number)
+	if number == "" {
+		return "", fmt.Errorf("%w: phone number is required", ErrInvalidPhoneNumber)
+	}
+
+	// Keep the digits, rejecting anything that is not a digit or a separator
+	international := strings.HasPrefix(number, "+")
+	if international {
+		number = number[1:]
+	}
+	var digits strings.Builder
+	for _, r := range number {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case strings.ContainsRune(" \t-./()", r):
+		default:
+			return "", fmt.Errorf("%w: unexpected character %q", ErrInvalidPhoneNumber, r)
+		}
+	}
+	normalized := digits.String()
+
+	// Apply the region's dialling plan to numbers without a plus sign
+	if !international {
+		if region == "" {
+			return "", fmt.Errorf("%w: a region is required for numbers without a country code", ErrInvalidPhoneNumber)
+		}
+		rules, ok := phoneRegions[strings.ToUpper(region)]
+		if !ok {
+			return "", fmt.Errorf("%w: unsupported region %q", ErrInvalidPhoneNumber, region)
+		}
+		switch {
+		case strings.HasPrefix(normalized, rules.internationalPrefix):
+			normalized = strings.TrimPrefix(normalized, rules.internationalPrefix)
+		case rules.trunkPrefix != "" && strings.HasPrefix(normalized, rules.trunkPrefix):
+			normalized = rules.callingCode + strings.TrimPrefix(normalized, rules.trunkPrefix)
+		default:
+			normalized = rules.callingCode + normalized
+		}
+	}
+
+	normalized = "+" + normalized
+	if !e164Regex.MatchString(normalized) {
+		return "", fmt.Errorf("%w: %s is not a valid E.164 number", ErrInvalidPhoneNumber, normalized)
+	}
+
+	return normalized, nil
+}
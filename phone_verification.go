@@ -0,0 +1,244 @@
+package accountslib
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrNoPhoneVerification is returned when a user has no phone verification in progress.
+	ErrNoPhoneVerification = errors.New("no pending phone verification")
+
+	// ErrPhoneVerificationExpired is returned when the code was sent too long ago. A new code must be requested.
+	ErrPhoneVerificationExpired = errors.New("phone verification code has expired")
+
+	// ErrTooManyPhoneVerificationAttempts is returned once the attempt limit of a code is used up.
+	// A new code must be requested.
+	ErrTooManyPhoneVerificationAttempts = errors.New("too many phone verification attempts")
+
+	// ErrInvalidPhoneVerificationCode is returned when the code does not match.
+	ErrInvalidPhoneVerificationCode = errors.New("invalid phone verification code")
+
+	// ErrPhoneVerificationThrottled is returned when a new code is requested before ResendInterval has passed.
+	ErrPhoneVerificationThrottled = errors.New("a verification code was sent recently; try again later")
+)
+
+// PhoneVerificationOptions configures SendPhoneVerificationCode. Zero fields take their
+// value from DefaultPhoneVerificationOptions.
+type PhoneVerificationOptions struct {
+	// CodeLength is the number of digits in a code.
+	CodeLength int
+	// TTL is how long a code can be used after it is sent.
+	TTL time.Duration
+	// MaxAttempts is how many codes may be tried before a new one must be requested.
+	MaxAttempts int
+	// ResendInterval is the minimum time between two codes sent to the same user.
+	ResendInterval time.Duration
+}
+
+// DefaultPhoneVerificationOptions are used for every option left at zero.
+var DefaultPhoneVerificationOptions = PhoneVerificationOptions{
+	CodeLength:     6,
+	TTL:            10 * time.Minute,
+	MaxAttempts:    5,
+	ResendInterval: time.Minute,
+}
+
+func (o PhoneVerificationOptions) withDefaults() PhoneVerificationOptions {
+	if o.CodeLength <= 0 {
+		o.CodeLength = DefaultPhoneVerificationOptions.CodeLength
+	}
+	if o.TTL <= 0 {
+		o.TTL = DefaultPhoneVerificationOptions.TTL
+	}
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = DefaultPhoneVerificationOptions.MaxAttempts
+	}
+	if o.ResendInterval <= 0 {
+		o.ResendInterval = DefaultPhoneVerificationOptions.ResendInterval
+	}
+	return o
+}
+
+// SendPhoneVerificationCodeInput represents the input data for sending a phone verification code.
+type SendPhoneVerificationCodeInput struct {
+	UserID      uuid.UUID
+	PhoneNumber string
+	// Region is the ISO 3166-1 alpha-2 region used to read numbers typed without a country code.
+	Region string
+}
+
+// PhoneVerification is a phone number waiting to be confirmed with the code sent to it.
+type PhoneVerification struct {
+	UserID      uuid.UUID `json:"user_id"`
+	PhoneNumber string    `json:"phone_number"`
+	Attempts    int       `json:"attempts"`
+	MaxAttempts int       `json:"max_attempts"`
+	SentAt      time.Time `json:"sent_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// phoneVerificationRecord is a phone verification as stored by the API. Only the hash of
+// the code is stored.
+type phoneVerificationRecord struct {
+	PhoneVerification
+	CodeHash string `json:"code_hash"`
+}
+
+// SendPhoneVerificationCode normalizes a phone number to E.164 and texts a one-time code to it
+// through the client's SMSSender. Sending a new code replaces any earlier one and resets the
+// attempt count, but only once opts.ResendInterval has passed since the previous code.
+func (c *Client) SendPhoneVerificationCode(ctx context.Context, input SendPhoneVerificationCodeInput, opts ...PhoneVerificationOptions) (*PhoneVerification, error) {
+	var options PhoneVerificationOptions
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+	options = options.withDefaults()
+
+	// Validate the input
+	if input.UserID == uuid.Nil {
+		return nil, errors.New("user ID is required")
+	}
+	phoneNumber, err := NormalizePhoneNumber(input.PhoneNumber, input.Region)
+	if err != nil {
+		return nil, err
+	}
+	if c.SMSSender == nil {
+		return nil, errors.New("no SMS sender configured")
+	}
+
+	// Throttle resends
+	existing, err := c.GetPhoneVerification(ctx, input.UserID)
+	if err != nil && !errors.Is(err, ErrNoPhoneVerification) {
+		return nil, err
+	}
+	if err == nil && time.Since(existing.SentAt) < options.ResendInterval {
+		return nil, ErrPhoneVerificationThrottled
+	}
+
+	// Store the new code
+	code, err := generatePhoneVerificationCode(options.CodeLength)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	record := phoneVerificationRecord{
+		PhoneVerification: PhoneVerification{
+			UserID:      input.UserID,
+			PhoneNumber: phoneNumber,
+			MaxAttempts: options.MaxAttempts,
+			SentAt:      now,
+			ExpiresAt:   now.Add(options.TTL),
+		},
+		CodeHash: hashSecret(phoneVerificationSecret(input.UserID, code)),
+	}
+	if err := c.doJSON(ctx, http.MethodPut, []string{"users", input.UserID.String(), "phone-verification"}, record, http.StatusOK, nil, ErrNoPhoneVerification); err != nil {
+		return nil, err
+	}
+
+	// Text the code
+	err = c.SMSSender.SendSMS(ctx, SMSMessage{
+		To:   phoneNumber,
+		Body: fmt.Sprintf("Your verification code is %s. It expires in %d minutes.", code, int(options.TTL.Minutes())),
+	})
+	if err != nil {
+		// Discard the undelivered code so the user is not throttled
+		_ = c.CancelPhoneVerification(ctx, input.UserID)
+		return nil, fmt.Errorf("unable to send verification code: %w", err)
+	}
+
+	return &record.PhoneVerification, nil
+}
+
+// ConfirmPhoneVerification checks code against the code last sent to the user. On a match the
+// verified number becomes the user's PhoneNumber and IsPhoneVerified is set. Each call counts
+// as an attempt; once the attempts run out or the code expires, a new code must be sent.
+func (c *Client) ConfirmPhoneVerification(ctx context.Context, userID uuid.UUID, code string) error {
+	if userID == uuid.Nil {
+		return errors.New("user ID is required")
+	}
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return ErrInvalidPhoneVerificationCode
+	}
+
+	// Record the attempt before comparing, so concurrent guesses cannot exceed the limit
+	var record phoneVerificationRecord
+	if err := c.doJSON(ctx, http.MethodPost, []string{"users", userID.String(), "phone-verification", "attempts"}, nil, http.StatusOK, &record, ErrNoPhoneVerification); err != nil {
+		return err
+	}
+
+	if !time.Now().Before(record.ExpiresAt) {
+		_ = c.CancelPhoneVerification(ctx, userID)
+		return ErrPhoneVerificationExpired
+	}
+	if record.Attempts > record.MaxAttempts {
+		_ = c.CancelPhoneVerification(ctx, userID)
+		return ErrTooManyPhoneVerificationAttempts
+	}
+	if subtle.ConstantTimeCompare([]byte(hashSecret(phoneVerificationSecret(userID, code))), []byte(record.CodeHash)) != 1 {
+		return ErrInvalidPhoneVerificationCode
+	}
+
+	// Apply the verified number
+	verified := true
+	if err := c.UpdateUser(userID, &UpdateUserPayload{PhoneNumber: &record.PhoneNumber, IsPhoneVerified: &verified}); err != nil {
+		return fmt.Errorf("unable to update phone number: %w", err)
+	}
+
+	if err := c.CancelPhoneVerification(ctx, userID); err != nil && !errors.Is(err, ErrNoPhoneVerification) {
+		return fmt.Errorf("phone number was verified but the code could not be cleared: %w", err)
+	}
+
+	return nil
+}
+
+// GetPhoneVerification returns the phone verification a user has in progress, or ErrNoPhoneVerification.
+func (c *Client) GetPhoneVerification(ctx context.Context, userID uuid.UUID) (*PhoneVerification, error) {
+	if userID == uuid.Nil {
+		return nil, errors.New("user ID is required")
+	}
+
+	var record phoneVerificationRecord
+	if err := c.doJSON(ctx, http.MethodGet, []string{"users", userID.String(), "phone-verification"}, nil, http.StatusOK, &record, ErrNoPhoneVerification); err != nil {
+		return nil, err
+	}
+	return &record.PhoneVerification, nil
+}
+
+// CancelPhoneVerification discards the code a user has in progress.
+func (c *Client) CancelPhoneVerification(ctx context.Context, userID uuid.UUID) error {
+	if userID == uuid.Nil {
+		return errors.New("user ID is required")
+	}
+
+	return c.doJSON(ctx, http.MethodDelete, []string{"users", userID.String(), "phone-verification"}, nil, http.StatusNoContent, nil, ErrNoPhoneVerification)
+}
+
+// generatePhoneVerificationCode returns a random numeric code of the given length.
+func generatePhoneVerificationCode(length int) (string, error) {
+	var code strings.Builder
+	for i := 0; i < length; i++ {
+		digit, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", fmt.Errorf("unable to generate verification code: %w", err)
+		}
+		code.WriteByte(byte('0' + digit.Int64()))
+	}
+	return code.String(), nil
+}
+
+// phoneVerificationSecret binds a code to its user before hashing, since six digits alone would
+// give the same digest for every user who drew them.
+func phoneVerificationSecret(userID uuid.UUID, code string) string {
+	return userID.String() + ":" + code
+}
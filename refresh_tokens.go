@@ -0,0 +1,219 @@
+package accountslib
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidRefreshToken is returned for refresh tokens that are unknown, expired or revoked.
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+
+	// ErrRefreshTokenReused is returned when a refresh token that was already exchanged is
+	// presented again. The whole token family has been revoked by the time it is returned.
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected; token family revoked")
+)
+
+// TokenPair is a short-lived access token together with the refresh token that renews it.
+type TokenPair struct {
+	AccessToken *Token
+	// RefreshToken is the plaintext refresh token. It is only available here.
+	RefreshToken       string
+	RefreshTokenExpiry time.Time
+	// FamilyID identifies the chain of refresh tokens descending from one login.
+	FamilyID uuid.UUID
+}
+
+// RefreshTokenRecord is a refresh token as stored by the API. Every refresh token exchanged
+// for a new pair is marked used; a used token is never valid again.
+type RefreshTokenRecord struct {
+	ID       uuid.UUID `json:"id"`
+	FamilyID uuid.UUID `json:"family_id"`
+	ParentID uuid.UUID `json:"parent_id"`
+	UserID   uuid.UUID `json:"user_id"`
+	// Scope is the scope of the access tokens the refresh token is exchanged for.
+	Scope     TokenScope `json:"scope"`
+	TokenHash string     `json:"token_hash"`
+	IssuedAt  time.Time  `json:"issued_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	// FamilyExpiresAt is when the whole family stops being refreshable, however recently it was rotated.
+	FamilyExpiresAt time.Time  `json:"family_expires_at"`
+	UsedAt          *time.Time `json:"used_at,omitempty"`
+	RevokedAt       *time.Time `json:"revoked_at,omitempty"`
+}
+
+// IssueTokenPair creates an access token with the given scope and a refresh token that starts
+// a new token family, typically on login. Lifetimes come from the client's TokenScopes for
+// scope and for ScopeRefresh; the family as a whole ends after the RefreshFamilyLifetime of scope.
+func (c *Client) IssueTokenPair(ctx context.Context, userID uuid.UUID, scope TokenScope) (*TokenPair, error) {
+	if userID == uuid.Nil {
+		return nil, errors.New("user ID is required")
+	}
+	if scope == "" || scope == ScopeRefresh {
+		return nil, errors.New("an access token scope is required")
+	}
+
+	familyLifetime, err := c.refreshFamilyLifetime(scope)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.issueTokenPair(ctx, userID, scope, uuid.New(), time.Now().Add(familyLifetime), nil)
+}
+
+// RefreshToken exchanges a refresh token for a new token pair. The refresh token is rotated:
+// the one presented is used up and the pair carries its replacement. Presenting a used refresh
+// token again means it has leaked, so every refresh token in its family is revoked and
+// ErrRefreshTokenReused is returned. Access tokens already issued remain valid until they
+// expire, which is why access token lifetimes should be short.
+//
+// The API marks the presented token used and stores its replacement in one call, so a request
+// that fails part way leaves the presented token usable and can safely be retried.
+func (c *Client) RefreshToken(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	if refreshToken == "" {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// Look up the refresh token
+	var record RefreshTokenRecord
+	err := c.doJSON(ctx, http.MethodPost, []string{"refresh-tokens", "lookup"}, map[string]string{"token_hash": hashSecret(refreshToken)}, http.StatusOK, &record, ErrInvalidRefreshToken)
+	if err != nil {
+		return nil, err
+	}
+	if record.RevokedAt != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	if record.UsedAt != nil {
+		return nil, c.revokeReusedTokenFamily(ctx, record.FamilyID)
+	}
+	now := time.Now()
+	if !now.Before(record.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// Families stored before they had an expiry end with their current token
+	familyExpiresAt := record.FamilyExpiresAt
+	if familyExpiresAt.IsZero() {
+		familyExpiresAt = record.ExpiresAt
+	}
+	if !now.Before(familyExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// Rotate it; the API refuses with 409 Conflict if another request got there first
+	pair, err := c.issueTokenPair(ctx, record.UserID, record.Scope, record.FamilyID, familyExpiresAt, &record)
+	if hasStatus(err, http.StatusConflict) {
+		return nil, c.revokeReusedTokenFamily(ctx, record.FamilyID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return pair, nil
+}
+
+// RevokeTokenFamily revokes every refresh token descending from the same login, for example
+// when the user signs out.
+func (c *Client) RevokeTokenFamily(ctx context.Context, familyID uuid.UUID) error {
+	if familyID == uuid.Nil {
+		return errors.New("family ID is required")
+	}
+
+	return c.doJSON(ctx, http.MethodPost, []string{"refresh-token-families", familyID.String(), "revoke"}, nil, http.StatusNoContent, nil, ErrInvalidRefreshToken)
+}
+
+// RevokeUserTokenFamilies revokes every refresh token family of a user, so that no device can
+// renew its access tokens.
+func (c *Client) RevokeUserTokenFamilies(ctx context.Context, userID uuid.UUID) error {
+	if userID == uuid.Nil {
+		return errors.New("user ID is required")
+	}
+
+	return c.doJSON(ctx, http.MethodPost, []string{"users", userID.String(), "refresh-token-families", "revoke"}, nil, http.StatusNoContent, nil, nil)
+}
+
+// revokeReusedTokenFamily revokes a family after reuse was detected and returns the error to report.
+func (c *Client) revokeReusedTokenFamily(ctx context.Context, familyID uuid.UUID) error {
+	if err := c.RevokeTokenFamily(ctx, familyID); err != nil && !errors.Is(err, ErrInvalidRefreshToken) {
+		return fmt.Errorf("%w, but revoking the family failed: %v", ErrRefreshTokenReused, err)
+	}
+	return ErrRefreshTokenReused
+}
+
+// issueTokenPair creates an access token and a refresh token in the given family. With a parent,
+// the parent is marked used in the same call that stores the new refresh token. When the refresh
+// token cannot be stored, the access token is deleted again.
+func (c *Client) issueTokenPair(ctx context.Context, userID uuid.UUID, scope TokenScope, familyID uuid.UUID, familyExpiresAt time.Time, parent *RefreshTokenRecord) (*TokenPair, error) {
+	refreshScope, err := c.tokenScope(ScopeRefresh)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := c.CreateToken(CreateTokenInput{UserID: userID, Scope: scope})
+	if err != nil {
+		return nil, fmt.Errorf("unable to create access token: %w", err)
+	}
+
+	// Generate the refresh token
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, fmt.Errorf("unable to generate refresh token: %w", err)
+	}
+	refreshToken := base64.RawURLEncoding.EncodeToString(raw)
+
+	now := time.Now()
+	record := RefreshTokenRecord{
+		ID:              uuid.New(),
+		FamilyID:        familyID,
+		UserID:          userID,
+		Scope:           scope,
+		TokenHash:       hashSecret(refreshToken),
+		IssuedAt:        now,
+		ExpiresAt:       now.Add(refreshScope.Lifetime),
+		FamilyExpiresAt: familyExpiresAt,
+	}
+	if record.ExpiresAt.After(familyExpiresAt) {
+		record.ExpiresAt = familyExpiresAt
+	}
+
+	segments := []string{"refresh-tokens"}
+	if parent != nil {
+		record.ParentID = parent.ID
+		segments = []string{"refresh-tokens", parent.ID.String(), "rotate"}
+	}
+	if err := c.doJSON(ctx, http.MethodPost, segments, record, http.StatusCreated, nil, ErrInvalidRefreshToken); err != nil {
+		_ = c.DeleteToken(DeleteTokenInput{UserID: userID, TokenID: accessToken.ID})
+		return nil, fmt.Errorf("unable to store refresh token: %w", err)
+	}
+
+	return &TokenPair{
+		AccessToken:        accessToken,
+		RefreshToken:       refreshToken,
+		RefreshTokenExpiry: record.ExpiresAt,
+		FamilyID:           familyID,
+	}, nil
+}
+
+// refreshFamilyLifetime returns how long a refresh token family started for access tokens of scope may last.
+func (c *Client) refreshFamilyLifetime(scope TokenScope) (time.Duration, error) {
+	accessScope, err := c.tokenScope(scope)
+	if err != nil {
+		return 0, err
+	}
+	if accessScope.RefreshFamilyLifetime > 0 {
+		return accessScope.RefreshFamilyLifetime, nil
+	}
+
+	refreshScope, err := c.tokenScope(ScopeRefresh)
+	if err != nil {
+		return 0, err
+	}
+	return refreshScope.Lifetime, nil
+}
@@ -0,0 +1,330 @@
+package accountslib
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// Headers added by SigningTransport and checked by RequestVerifier.
+const (
+	SignatureKeyIDHeader     = "X-Signature-Key-Id"
+	SignatureTimestampHeader = "X-Signature-Timestamp"
+	SignatureNonceHeader     = "X-Signature-Nonce"
+	ContentDigestHeader      = "X-Content-Sha256"
+	SignatureHeader          = "X-Signature"
+)
+
+// signatureVersion prefixes signatures so that the scheme can change without ambiguity.
+const signatureVersion = "v1"
+
+var (
+	// ErrSignatureMissing is returned for requests without the signature headers.
+	ErrSignatureMissing = errors.New("request signature missing")
+
+	// ErrSignatureInvalid is returned for signatures that do not match the request or use an unknown key.
+	ErrSignatureInvalid = errors.New("request signature invalid")
+
+	// ErrSignatureExpired is returned for requests whose timestamp is outside the allowed clock skew.
+	ErrSignatureExpired = errors.New("request signature timestamp outside allowed skew")
+
+	// ErrSignatureReplayed is returned for requests whose nonce was already seen.
+	ErrSignatureReplayed = errors.New("request signature replayed")
+)
+
+// SigningTransport is an http.RoundTripper that signs every request with HMAC-SHA256 over its
+// method, path and query, body digest, timestamp and a random nonce. Services verify the
+// signature with RequestVerifier.
+type SigningTransport struct {
+	// KeyID tells the receiver which secret to verify with, so that secrets can be rotated.
+	KeyID  string
+	Secret []byte
+	// Base is the transport that sends the signed request. Defaults to http.DefaultTransport.
+	Base http.RoundTripper
+	// Clock defaults to SystemClock.
+	Clock Clock
+}
+
+// RoundTrip signs a copy of the request and sends it.
+func (t *SigningTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := requestBody(req)
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("unable to generate nonce: %w", err)
+	}
+
+	clock := t.Clock
+	if clock == nil {
+		clock = SystemClock
+	}
+
+	// Sign a copy; a RoundTripper must not modify the caller's request
+	signed := req.Clone(req.Context())
+	signed.Body = io.NopCloser(bytes.NewReader(body))
+	signed.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+
+	digest := sha256.Sum256(body)
+	signed.Header.Set(SignatureKeyIDHeader, t.KeyID)
+	signed.Header.Set(SignatureTimestampHeader, strconv.FormatInt(clock.Now().Unix(), 10))
+	signed.Header.Set(SignatureNonceHeader, base64.RawURLEncoding.EncodeToString(nonce))
+	signed.Header.Set(ContentDigestHeader, hex.EncodeToString(digest[:]))
+	signed.Header.Set(SignatureHeader, signatureVersion+"="+signRequest(t.Secret, signed))
+
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(signed)
+}
+
+// UseRequestSigning makes the client sign every request with a SigningTransport. The client's
+// HttpClient is replaced by a copy with the signing transport; it works together with
+// UseTokenSource, and token requests made by ServiceAccountTokenSource are signed as well.
+func (c *Client) UseRequestSigning(keyID string, secret []byte) {
+	httpClient := &http.Client{Timeout: time.Second * 10}
+	if c.HttpClient != nil {
+		copied := *c.HttpClient
+		httpClient = &copied
+	}
+
+	// Sign beneath the token transport, so that token requests are signed as well
+	if transport, ok := httpClient.Transport.(*oauth2.Transport); ok {
+		httpClient.Transport = &oauth2.Transport{Source: transport.Source, Base: &SigningTransport{KeyID: keyID, Secret: secret, Base: transport.Base}}
+	} else {
+		httpClient.Transport = &SigningTransport{KeyID: keyID, Secret: secret, Base: httpClient.Transport}
+	}
+	c.HttpClient = httpClient
+}
+
+// ReplayCache remembers the nonces of verified requests.
+type ReplayCache interface {
+	// Add records key until expiresAt and reports false when it was already recorded.
+	Add(key string, expiresAt time.Time) bool
+}
+
+// MemoryReplayCache is a ReplayCache held in memory. It is safe for concurrent use. Services
+// running several replicas need a shared cache instead, or a replay can reach another replica.
+type MemoryReplayCache struct {
+	mu        sync.Mutex
+	keys      map[string]time.Time
+	lastPrune time.Time
+}
+
+// Add records key until expiresAt and reports false when it was already recorded.
+func (c *MemoryReplayCache) Add(key string, expiresAt time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if c.keys == nil {
+		c.keys = make(map[string]time.Time)
+	}
+
+	// Drop expired keys now and then, so the cache does not grow without bound
+	if now.Sub(c.lastPrune) > time.Minute {
+		for k, expiry := range c.keys {
+			if !now.Before(expiry) {
+				delete(c.keys, k)
+			}
+		}
+		c.lastPrune = now
+	}
+
+	if expiry, ok := c.keys[key]; ok && now.Before(expiry) {
+		return false
+	}
+	c.keys[key] = expiresAt
+	return true
+}
+
+// RequestVerifierOptions configures a RequestVerifier. Zero fields take the defaults described on each field.
+type RequestVerifierOptions struct {
+	// Secrets maps key IDs to the secrets they sign with. It is required.
+	Secrets map[string][]byte
+	// MaxSkew is how far a request's timestamp may be from the verifier's clock. Defaults to five minutes.
+	MaxSkew time.Duration
+	// ReplayCache defaults to a MemoryReplayCache.
+	ReplayCache ReplayCache
+	// MaxBodyBytes limits the body read to check its digest. Defaults to 10 MiB.
+	MaxBodyBytes int64
+	// Clock defaults to SystemClock.
+	Clock Clock
+}
+
+func (o RequestVerifierOptions) withDefaults() RequestVerifierOptions {
+	if o.MaxSkew <= 0 {
+		o.MaxSkew = 5 * time.Minute
+	}
+	if o.ReplayCache == nil {
+		o.ReplayCache = &MemoryReplayCache{}
+	}
+	if o.MaxBodyBytes <= 0 {
+		o.MaxBodyBytes = 10 << 20
+	}
+	if o.Clock == nil {
+		o.Clock = SystemClock
+	}
+	return o
+}
+
+// RequestVerifier checks the signatures added by SigningTransport, for services that receive
+// calls from a Client. It is safe for concurrent use.
+type RequestVerifier struct {
+	options RequestVerifierOptions
+}
+
+// NewRequestVerifier returns a verifier for requests signed with the given secrets.
+func NewRequestVerifier(opts RequestVerifierOptions) (*RequestVerifier, error) {
+	if len(opts.Secrets) == 0 {
+		return nil, errors.New("at least one secret is required")
+	}
+
+	options := opts.withDefaults()
+	options.Secrets = make(map[string][]byte, len(opts.Secrets))
+	for keyID, secret := range opts.Secrets {
+		if len(secret) == 0 {
+			return nil, fmt.Errorf("secret for key %q is empty", keyID)
+		}
+		options.Secrets[keyID] = secret
+	}
+
+	return &RequestVerifier{options: options}, nil
+}
+
+// Verify checks the signature of a request. The body is read and replaced, so handlers can
+// still read it afterwards.
+func (v *RequestVerifier) Verify(r *http.Request) error {
+	keyID := r.Header.Get(SignatureKeyIDHeader)
+	timestamp := r.Header.Get(SignatureTimestampHeader)
+	nonce := r.Header.Get(SignatureNonceHeader)
+	digest := r.Header.Get(ContentDigestHeader)
+	signature := r.Header.Get(SignatureHeader)
+	if timestamp == "" || nonce == "" || digest == "" || signature == "" {
+		return ErrSignatureMissing
+	}
+
+	secret, ok := v.options.Secrets[keyID]
+	if !ok {
+		return fmt.Errorf("%w: unknown key ID %q", ErrSignatureInvalid, keyID)
+	}
+
+	// Check the timestamp
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: malformed timestamp", ErrSignatureInvalid)
+	}
+	signedAt := time.Unix(unix, 0)
+	skew := v.options.Clock.Now().Sub(signedAt)
+	if skew > v.options.MaxSkew || skew < -v.options.MaxSkew {
+		return ErrSignatureExpired
+	}
+
+	// Check the body digest
+	body, err := io.ReadAll(io.LimitReader(r.Body, v.options.MaxBodyBytes+1))
+	if err != nil {
+		return fmt.Errorf("unable to read request body: %w", err)
+	}
+	if int64(len(body)) > v.options.MaxBodyBytes {
+		return fmt.Errorf("%w: body too large", ErrSignatureInvalid)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	sum := sha256.Sum256(body)
+	if !hmac.Equal([]byte(digest), []byte(hex.EncodeToString(sum[:]))) {
+		return fmt.Errorf("%w: body digest mismatch", ErrSignatureInvalid)
+	}
+
+	// Check the signature
+	expected := signatureVersion + "=" + signRequest(secret, r)
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return ErrSignatureInvalid
+	}
+
+	// Only a valid signature may claim a nonce, or forged requests could burn genuine ones
+	if !v.options.ReplayCache.Add(keyID+":"+nonce, signedAt.Add(v.options.MaxSkew)) {
+		return ErrSignatureReplayed
+	}
+
+	return nil
+}
+
+// Middleware rejects requests without a valid signature with 401 Unauthorized before they reach next.
+func (v *RequestVerifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := v.Verify(r); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// signRequest returns the hex HMAC-SHA256 of the request's canonical form, which is built from
+// headers already set on it.
+func signRequest(secret []byte, r *http.Request) string {
+	target := r.URL.EscapedPath()
+	if target == "" {
+		target = "/"
+	}
+	if r.URL.RawQuery != "" {
+		target += "?" + r.URL.RawQuery
+	}
+
+	canonical := strings.Join([]string{
+		r.Method,
+		target,
+		r.Header.Get(ContentDigestHeader),
+		r.Header.Get(SignatureTimestampHeader),
+		r.Header.Get(SignatureNonceHeader),
+		r.Header.Get(SignatureKeyIDHeader),
+	}, "\n")
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(canonical))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// requestBody returns the body of an outgoing request, from a fresh copy when GetBody allows it.
+func requestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("unable to read request body: %w", err)
+		}
+		defer body.Close()
+		return io.ReadAll(body)
+	}
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read request body: %w", err)
+	}
+	return body, nil
+}
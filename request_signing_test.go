@@ -0,0 +1,156 @@
+package accountslib
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// captureTransport records the request it is asked to send instead of sending it.
+type captureTransport struct {
+	req *http.Request
+}
+
+func (t *captureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.req = req
+	return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+}
+
+// signedHeaders signs a POST to /api/users with the given body at the clock's time and returns
+// the headers SigningTransport added.
+func signedHeaders(t *testing.T, clock Clock, body string) http.Header {
+	t.Helper()
+	capture := &captureTransport{}
+	transport := &SigningTransport{KeyID: "key-1", Secret: []byte("secret"), Base: capture, Clock: clock}
+
+	req, err := http.NewRequest(http.MethodPost, "https://accounts.example.com/api/users?page=2", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := transport.RoundTrip(req); err != nil {
+		t.Fatalf("RoundTrip returned error: %v", err)
+	}
+	return capture.req.Header
+}
+
+// incomingRequest rebuilds a signed request as a server receives it.
+func incomingRequest(header http.Header, body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/api/users?page=2", strings.NewReader(body))
+	r.Header = header.Clone()
+	return r
+}
+
+func TestRequestVerifier(t *testing.T) {
+	const body = `{"email":"user@example.com"}`
+
+	tests := []struct {
+		name string
+		// skew moves the verifier's clock relative to the signer's.
+		skew time.Duration
+		// modify changes the incoming request after signing.
+		modify func(r *http.Request)
+		// replay verifies the request a second time and checks the second result.
+		replay bool
+		want   error
+	}{
+		{name: "valid"},
+		{name: "within skew ahead", skew: 4 * time.Minute},
+		{name: "within skew behind", skew: -4 * time.Minute},
+		{name: "too old", skew: 6 * time.Minute, want: ErrSignatureExpired},
+		{name: "from the future", skew: -6 * time.Minute, want: ErrSignatureExpired},
+		{name: "replayed", replay: true, want: ErrSignatureReplayed},
+		{
+			name:   "missing signature",
+			modify: func(r *http.Request) { r.Header.Del(SignatureHeader) },
+			want:   ErrSignatureMissing,
+		},
+		{
+			name:   "unknown key",
+			modify: func(r *http.Request) { r.Header.Set(SignatureKeyIDHeader, "key-2") },
+			want:   ErrSignatureInvalid,
+		},
+		{
+			name:   "tampered body",
+			modify: func(r *http.Request) { r.Body = io.NopCloser(strings.NewReader(`{"email":"admin@example.com"}`)) },
+			want:   ErrSignatureInvalid,
+		},
+		{
+			name:   "tampered query",
+			modify: func(r *http.Request) { r.URL.RawQuery = "page=3" },
+			want:   ErrSignatureInvalid,
+		},
+		{
+			name: "tampered timestamp",
+			modify: func(r *http.Request) {
+				unix, _ := strconv.ParseInt(r.Header.Get(SignatureTimestampHeader), 10, 64)
+				r.Header.Set(SignatureTimestampHeader, strconv.FormatInt(unix+1, 10))
+			},
+			want: ErrSignatureInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// MemoryReplayCache expires nonces by the wall clock, so sign at the current time
+			signer := newFakeClock()
+			signer.now = time.Now()
+			header := signedHeaders(t, signer, body)
+
+			clock := newFakeClock()
+			clock.now = signer.now.Add(tt.skew)
+			verifier, err := NewRequestVerifier(RequestVerifierOptions{
+				Secrets: map[string][]byte{"key-1": []byte("secret")},
+				Clock:   clock,
+			})
+			if err != nil {
+				t.Fatalf("NewRequestVerifier returned error: %v", err)
+			}
+
+			r := incomingRequest(header, body)
+			if tt.modify != nil {
+				tt.modify(r)
+			}
+			err = verifier.Verify(r)
+			if tt.replay {
+				if err != nil {
+					t.Fatalf("first Verify returned error: %v", err)
+				}
+				err = verifier.Verify(incomingRequest(header, body))
+			}
+
+			if tt.want == nil && err != nil {
+				t.Errorf("Verify returned error: %v", err)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("Verify returned %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestVerifierKeepsBody(t *testing.T) {
+	const body = `{"email":"user@example.com"}`
+	clock := newFakeClock()
+
+	verifier, err := NewRequestVerifier(RequestVerifierOptions{
+		Secrets: map[string][]byte{"key-1": []byte("secret")},
+		Clock:   clock,
+	})
+	if err != nil {
+		t.Fatalf("NewRequestVerifier returned error: %v", err)
+	}
+
+	r := incomingRequest(signedHeaders(t, clock, body), body)
+	if err := verifier.Verify(r); err != nil {
+		t.Fatalf("Verify returned error: %v", err)
+	}
+	read, err := io.ReadAll(r.Body)
+	if err != nil || string(read) != body {
+		t.Errorf("handler read body %q, %v; want %q", read, err, body)
+	}
+}
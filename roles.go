@@ -196,8 +196,13 @@ func (c *Client) GetRoleByID(roleID uuid.UUID) (*Role, error) {
 }
 
 func (c *Client) GetRoleByName(roleName string) (*Role, error) {
+	return c.getRoleByName(context.Background(), roleName)
+}
+
+// getRoleByName is GetRoleByName bound to ctx.
+func (c *Client) getRoleByName(ctx context.Context, roleName string) (*Role, error) {
 	// Create a new HTTP request
-	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/api/roles/%s", c.BaseURL, url.PathEscape(roleName)), nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/api/roles/%s", c.BaseURL, url.PathEscape(roleName)), nil)
 	if err != nil {
 		return nil, fmt.Errorf("unable to create new request: %w", err)
 	}
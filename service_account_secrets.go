@@ -0,0 +1,136 @@
+package accountslib
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrServiceAccountSecretNotFound is returned for unknown service accounts and secret IDs.
+var ErrServiceAccountSecretNotFound = errors.New("service account secret not found")
+
+// ServiceAccountSecret is one of the secrets a service account can authenticate with. Several
+// secrets are valid at once while a rotation is in progress.
+type ServiceAccountSecret struct {
+	ID               uuid.UUID `json:"id"`
+	ServiceAccountID uuid.UUID `json:"service_account_id"`
+	// Fingerprint identifies the secret without revealing it, for matching against client configuration.
+	Fingerprint string    `json:"fingerprint"`
+	CreatedAt   time.Time `json:"created_at"`
+	// ExpiresAt is set once the secret has been rotated out and is only valid for the overlap window.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+
+	// Secret is the plaintext secret, which RotateServiceAccountSecret hands out once.
+	Secret string `json:"-"`
+}
+
+// Active reports whether the secret can still be used at the given time.
+func (s *ServiceAccountSecret) Active(now time.Time) bool {
+	if s.RevokedAt != nil {
+		return false
+	}
+	return s.ExpiresAt == nil || now.Before(*s.ExpiresAt)
+}
+
+// serviceAccountSecretPayload is a new secret as sent to the API.
+type serviceAccountSecretPayload struct {
+	ServiceAccountSecret
+	SecretHash string `json:"secret_hash"`
+	// PreviousExpiresAt ends the overlap window of every secret that was active before this one.
+	PreviousExpiresAt time.Time `json:"previous_expires_at"`
+}
+
+// RotateServiceAccountSecret issues a new secret for a service account without changing its ID.
+// The secrets that were active before stay valid for overlap, giving every client time to switch
+// over; an overlap of zero invalidates them at once. The returned secret carries the plaintext
+// Secret, which cannot be retrieved again.
+func (c *Client) RotateServiceAccountSecret(ctx context.Context, id uuid.UUID, overlap time.Duration) (*ServiceAccountSecret, error) {
+	if id == uuid.Nil {
+		return nil, errors.New("service account ID is required")
+	}
+	if overlap < 0 {
+		return nil, errors.New("overlap must not be negative")
+	}
+
+	// Generate the secret
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, fmt.Errorf("unable to generate secret: %w", err)
+	}
+	secret := base64.RawURLEncoding.EncodeToString(raw)
+
+	now := time.Now()
+	payload := serviceAccountSecretPayload{
+		ServiceAccountSecret: ServiceAccountSecret{
+			ID:               uuid.New(),
+			ServiceAccountID: id,
+			Fingerprint:      ServiceAccountSecretFingerprint(secret),
+			CreatedAt:        now,
+		},
+		SecretHash:        hashSecret(secret),
+		PreviousExpiresAt: now.Add(overlap),
+	}
+
+	var created ServiceAccountSecret
+	if err := c.doJSON(ctx, http.MethodPost, []string{"service-accounts", id.String(), "secrets"}, payload, http.StatusCreated, &created, ErrServiceAccountSecretNotFound); err != nil {
+		return nil, err
+	}
+	created.Secret = secret
+
+	return &created, nil
+}
+
+// ListServiceAccountSecrets returns the secrets a service account can currently authenticate
+// with, newest first.
+func (c *Client) ListServiceAccountSecrets(ctx context.Context, id uuid.UUID) ([]ServiceAccountSecret, error) {
+	if id == uuid.Nil {
+		return nil, errors.New("service account ID is required")
+	}
+
+	var secrets []ServiceAccountSecret
+	if err := c.doJSON(ctx, http.MethodGet, []string{"service-accounts", id.String(), "secrets"}, nil, http.StatusOK, &secrets, ErrServiceAccountSecretNotFound); err != nil {
+		return nil, err
+	}
+
+	// The server keeps rotated secrets for a while after their overlap window ends
+	now := time.Now()
+	active := secrets[:0]
+	for _, secret := range secrets {
+		if secret.Active(now) {
+			active = append(active, secret)
+		}
+	}
+	sort.Slice(active, func(i, j int) bool {
+		return active[i].CreatedAt.After(active[j].CreatedAt)
+	})
+
+	return active, nil
+}
+
+// RevokeServiceAccountSecret invalidates one secret of a service account immediately, for
+// example when it has leaked. Revoking the only active secret locks the service account out
+// until it is rotated again.
+func (c *Client) RevokeServiceAccountSecret(ctx context.Context, id uuid.UUID, secretID uuid.UUID) error {
+	if id == uuid.Nil || secretID == uuid.Nil {
+		return errors.New("service account ID and secret ID are required")
+	}
+
+	return c.doJSON(ctx, http.MethodDelete, []string{"service-accounts", id.String(), "secrets", secretID.String()}, nil, http.StatusNoContent, nil, ErrServiceAccountSecretNotFound)
+}
+
+// ServiceAccountSecretFingerprint returns the fingerprint of a service account secret, as listed
+// by ListServiceAccountSecrets.
+func ServiceAccountSecretFingerprint(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return "SHA256:" + hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,266 @@
+package accountslib
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// sessionTouchInterval is how stale LastSeenAt may get before AuthenticateSession updates it,
+// so that busy sessions do not cause a write on every request.
+const sessionTouchInterval = time.Minute
+
+var (
+	// ErrSessionNotFound is returned for unknown session IDs and tokens.
+	ErrSessionNotFound = errors.New("session not found")
+
+	// ErrSessionExpired is returned for sessions that were revoked or timed out.
+	ErrSessionExpired = errors.New("session has expired")
+)
+
+// SessionOptions configures the timeouts of a new session. Zero fields take their value from
+// DefaultSessionOptions.
+type SessionOptions struct {
+	// IdleTimeout ends a session that has not been used for this long.
+	IdleTimeout time.Duration
+	// AbsoluteTimeout ends a session this long after it was created, however active it is.
+	AbsoluteTimeout time.Duration
+}
+
+// DefaultSessionOptions are used for every option left at zero.
+var DefaultSessionOptions = SessionOptions{
+	IdleTimeout:     24 * time.Hour,
+	AbsoluteTimeout: 30 * 24 * time.Hour,
+}
+
+func (o SessionOptions) withDefaults() SessionOptions {
+	if o.IdleTimeout <= 0 {
+		o.IdleTimeout = DefaultSessionOptions.IdleTimeout
+	}
+	if o.AbsoluteTimeout <= 0 {
+		o.AbsoluteTimeout = DefaultSessionOptions.AbsoluteTimeout
+	}
+	return o
+}
+
+// Session is a signed-in device of a user.
+type Session struct {
+	ID         uuid.UUID  `json:"id"`
+	UserID     uuid.UUID  `json:"user_id"`
+	Device     string     `json:"device,omitempty"`
+	IPAddress  string     `json:"ip_address,omitempty"`
+	UserAgent  string     `json:"user_agent,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	// IdleTimeout is the idle timeout the session was created with. It is sent to the API in
+	// whole seconds.
+	IdleTimeout time.Duration `json:"idle_timeout"`
+
+	// Token is the secret the device presents to AuthenticateSession. CreateSession returns it
+	// once and it cannot be listed later.
+	Token string `json:"-"`
+}
+
+// Active reports whether the session can still be used at the given time.
+func (s *Session) Active(now time.Time) bool {
+	if s.RevokedAt != nil || !now.Before(s.ExpiresAt) {
+		return false
+	}
+	return s.IdleTimeout <= 0 || now.Before(s.LastSeenAt.Add(s.IdleTimeout))
+}
+
+// CreateSessionInput represents the input data for creating a session on login.
+type CreateSessionInput struct {
+	UserID    uuid.UUID
+	Device    string
+	IPAddress string
+	UserAgent string
+}
+
+// sessionFields is a Session without its JSON methods.
+type sessionFields Session
+
+// sessionJSON is the wire form of a Session, with the idle timeout in seconds.
+type sessionJSON struct {
+	sessionFields
+	IdleTimeout int64 `json:"idle_timeout"`
+}
+
+func newSessionJSON(s Session) sessionJSON {
+	return sessionJSON{sessionFields: sessionFields(s), IdleTimeout: int64(s.IdleTimeout / time.Second)}
+}
+
+func (s Session) MarshalJSON() ([]byte, error) {
+	return json.Marshal(newSessionJSON(s))
+}
+
+func (s *Session) UnmarshalJSON(data []byte) error {
+	var wire sessionJSON
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return err
+	}
+	*s = Session(wire.sessionFields)
+	s.IdleTimeout = time.Duration(wire.IdleTimeout) * time.Second
+	return nil
+}
+
+// sessionPayload is a session as sent to the API, with the hash of its token.
+type sessionPayload struct {
+	sessionJSON
+	TokenHash string `json:"token_hash"`
+}
+
+// CreateSession starts a session for a user who has just logged in. The returned session carries
+// the Token to hand to the device; it cannot be retrieved again.
+func (c *Client) CreateSession(ctx context.Context, input CreateSessionInput, opts ...SessionOptions) (*Session, error) {
+	var options SessionOptions
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+	options = options.withDefaults()
+
+	if input.UserID == uuid.Nil {
+		return nil, errors.New("user ID is required")
+	}
+
+	// Generate the session token
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, fmt.Errorf("unable to generate session token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	now := time.Now()
+	payload := sessionPayload{
+		sessionJSON: newSessionJSON(Session{
+			ID:          uuid.New(),
+			UserID:      input.UserID,
+			Device:      input.Device,
+			IPAddress:   input.IPAddress,
+			UserAgent:   input.UserAgent,
+			CreatedAt:   now,
+			LastSeenAt:  now,
+			ExpiresAt:   now.Add(options.AbsoluteTimeout),
+			IdleTimeout: options.IdleTimeout,
+		}),
+		TokenHash: hashSecret(token),
+	}
+
+	var session Session
+	if err := c.doJSON(ctx, http.MethodPost, []string{"users", input.UserID.String(), "sessions"}, payload, http.StatusCreated, &session, ErrSessionNotFound); err != nil {
+		return nil, err
+	}
+	session.Token = token
+
+	return &session, nil
+}
+
+// AuthenticateSession returns the active session a token belongs to and records that it was
+// just used. Revoked and timed out sessions yield ErrSessionExpired.
+func (c *Client) AuthenticateSession(ctx context.Context, token string) (*Session, error) {
+	if token == "" {
+		return nil, ErrSessionNotFound
+	}
+
+	var session Session
+	err := c.doJSON(ctx, http.MethodPost, []string{"sessions", "lookup"}, map[string]string{"token_hash": hashSecret(token)}, http.StatusOK, &session, ErrSessionNotFound)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if !session.Active(now) {
+		return nil, ErrSessionExpired
+	}
+
+	// Keep the idle timeout from ending a session that is in use
+	if now.Sub(session.LastSeenAt) >= sessionTouchInterval {
+		err := c.doJSON(ctx, http.MethodPatch, []string{"users", session.UserID.String(), "sessions", session.ID.String()}, map[string]time.Time{"last_seen_at": now}, http.StatusOK, nil, ErrSessionNotFound)
+		if err != nil {
+			return nil, fmt.Errorf("unable to update session: %w", err)
+		}
+		session.LastSeenAt = now
+	}
+
+	return &session, nil
+}
+
+// ListSessions returns the active sessions of a user, most recently used first.
+func (c *Client) ListSessions(ctx context.Context, userID uuid.UUID) ([]Session, error) {
+	if userID == uuid.Nil {
+		return nil, errors.New("user ID is required")
+	}
+
+	var sessions []Session
+	if err := c.doJSON(ctx, http.MethodGet, []string{"users", userID.String(), "sessions"}, nil, http.StatusOK, &sessions, ErrSessionNotFound); err != nil {
+		return nil, err
+	}
+
+	// Timed-out sessions stay on the server until they are purged; leave them out
+	now := time.Now()
+	active := sessions[:0]
+	for _, session := range sessions {
+		if session.Active(now) {
+			active = append(active, session)
+		}
+	}
+	sort.Slice(active, func(i, j int) bool {
+		return active[i].LastSeenAt.After(active[j].LastSeenAt)
+	})
+
+	return active, nil
+}
+
+// RevokeSession signs a single device out.
+func (c *Client) RevokeSession(ctx context.Context, userID uuid.UUID, sessionID uuid.UUID) error {
+	if userID == uuid.Nil || sessionID == uuid.Nil {
+		return errors.New("user ID and session ID are required")
+	}
+
+	return c.doJSON(ctx, http.MethodDelete, []string{"users", userID.String(), "sessions", sessionID.String()}, nil, http.StatusNoContent, nil, ErrSessionNotFound)
+}
+
+// RevokeAllOtherSessions signs out every device of a user except the one holding
+// currentSessionID, and returns the number of sessions revoked.
+func (c *Client) RevokeAllOtherSessions(ctx context.Context, userID uuid.UUID, currentSessionID uuid.UUID) (int, error) {
+	if userID == uuid.Nil || currentSessionID == uuid.Nil {
+		return 0, errors.New("user ID and current session ID are required")
+	}
+
+	var result struct {
+		Revoked int `json:"revoked"`
+	}
+	err := c.doJSON(ctx, http.MethodPost, []string{"users", userID.String(), "sessions", "revoke-others"}, map[string]uuid.UUID{"keep": currentSessionID}, http.StatusOK, &result, ErrSessionNotFound)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.Revoked, nil
+}
+
+// RevokeAllSessions signs out every device of a user, and returns the number of sessions revoked.
+func (c *Client) RevokeAllSessions(ctx context.Context, userID uuid.UUID) (int, error) {
+	if userID == uuid.Nil {
+		return 0, errors.New("user ID is required")
+	}
+
+	var result struct {
+		Revoked int `json:"revoked"`
+	}
+	err := c.doJSON(ctx, http.MethodPost, []string{"users", userID.String(), "sessions", "revoke-all"}, nil, http.StatusOK, &result, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.Revoked, nil
+}
@@ -0,0 +1,55 @@
+package accountslib
+
+import (
+	"context"
+	"sync"
+)
+
+// SMSMessage is a text message to a single E.164 phone number.
+type SMSMessage struct {
+	To   string
+	Body string
+}
+
+// SMSSender delivers text messages on behalf of the client.
+type SMSSender interface {
+	SendSMS(ctx context.Context, message SMSMessage) error
+}
+
+// MemorySMSSender keeps sent messages in memory instead of delivering them. It is meant
+// for tests and local development. The zero value is ready to use.
+type MemorySMSSender struct {
+	mu       sync.Mutex
+	messages []SMSMessage
+}
+
+// SendSMS records message.
+func (s *MemorySMSSender) SendSMS(ctx context.Context, message SMSMessage) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, message)
+	return nil
+}
+
+// Messages returns every message sent so far, oldest first.
+func (s *MemorySMSSender) Messages() []SMSMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]SMSMessage(nil), s.messages...)
+}
+
+// LastMessage returns the most recent message sent to the given number.
+func (s *MemorySMSSender) LastMessage(to string) (SMSMessage, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := len(s.messages) - 1; i >= 0; i-- {
+		if s.messages[i].To == to {
+			return s.messages[i], true
+		}
+	}
+	return SMSMessage{}, false
+}
@@ -0,0 +1,282 @@
+package accountslib
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// spkiPinPrefix starts every SPKI pin, as in HTTP Public Key Pinning.
+const spkiPinPrefix = "sha256/"
+
+// PinMismatchError is returned when the server's certificate chain matches none of the
+// configured SPKI pins.
+type PinMismatchError struct {
+	// ServerName is the name the connection was made to. It is empty for IP addresses.
+	ServerName string
+	// Presented are the pins of every certificate in the server's verified chains.
+	Presented []string
+	// Expected are the configured pins and backup pins.
+	Expected []string
+}
+
+func (e *PinMismatchError) Error() string {
+	target := ""
+	if e.ServerName != "" {
+		target = " for " + e.ServerName
+	}
+	return fmt.Sprintf("certificate pin mismatch%s: presented [%s], expected one of [%s]",
+		target, strings.Join(e.Presented, ", "), strings.Join(e.Expected, ", "))
+}
+
+// TLSOptions configures the TLS connections to the accounts server.
+type TLSOptions struct {
+	// ClientCertFile and ClientKeyFile hold the PEM client certificate and key for mutual TLS.
+	// Both files are checked on every handshake and reloaded when they change, so certificates
+	// can be renewed without a restart.
+	ClientCertFile string
+	ClientKeyFile  string
+	// RootCAs are the CAs trusted to sign the server certificate. Nil means the system pool,
+	// unless CAFile is set.
+	RootCAs *x509.CertPool
+	// CAFile holds PEM CA certificates to trust, in addition to RootCAs when it is set.
+	CAFile string
+	// Pins are SPKI pins of the form "sha256/<base64 digest>". When set, a certificate in the
+	// server's chain must match one of Pins or BackupPins.
+	Pins []string
+	// BackupPins are pins for keys not yet in use, so that the server key can be replaced
+	// without locking clients out. At least one is required when Pins is set.
+	BackupPins []string
+}
+
+// NewTLSConfig returns a TLS configuration applying the options.
+func NewTLSConfig(opts TLSOptions) (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12, RootCAs: opts.RootCAs}
+
+	// Trust the custom CAs
+	if opts.CAFile != "" {
+		pem, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if opts.RootCAs != nil {
+			pool = opts.RootCAs.Clone()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", opts.CAFile)
+		}
+		config.RootCAs = pool
+	}
+
+	// Present the client certificate
+	if opts.ClientCertFile != "" || opts.ClientKeyFile != "" {
+		if opts.ClientCertFile == "" || opts.ClientKeyFile == "" {
+			return nil, errors.New("client certificate and key files must be set together")
+		}
+		reloader, err := newCertificateReloader(opts.ClientCertFile, opts.ClientKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.GetClientCertificate = reloader.getClientCertificate
+	}
+
+	// Check the pins
+	if len(opts.Pins) > 0 {
+		if len(opts.BackupPins) == 0 {
+			return nil, errors.New("at least one backup pin is required when pinning")
+		}
+		expected := append(append([]string(nil), opts.Pins...), opts.BackupPins...)
+		for _, pin := range expected {
+			if err := validateSPKIPin(pin); err != nil {
+				return nil, err
+			}
+		}
+		config.VerifyConnection = func(state tls.ConnectionState) error {
+			return verifySPKIPins(state, expected)
+		}
+	} else if len(opts.BackupPins) > 0 {
+		return nil, errors.New("backup pins require pins")
+	}
+
+	return config, nil
+}
+
+// NewTLSHTTPClient returns an HTTP client whose connections apply the options, for use with NewClient.
+func NewTLSHTTPClient(opts TLSOptions) (*http.Client, error) {
+	config, err := NewTLSConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = config
+
+	return &http.Client{Transport: transport, Timeout: time.Second * 10}, nil
+}
+
+// UseTLS makes the client connect with the given TLS options. The client's HttpClient is
+// replaced by a copy whose underlying transport applies them, keeping any token source or
+// request signing. Token requests made by ServiceAccountTokenSource use it too.
+// It returns an error when the HttpClient uses a custom transport it cannot rewrap; configure
+// TLS on that transport directly with NewTLSConfig instead.
+func (c *Client) UseTLS(opts TLSOptions) error {
+	config, err := NewTLSConfig(opts)
+	if err != nil {
+		return err
+	}
+
+	httpClient := &http.Client{Timeout: time.Second * 10}
+	if c.HttpClient != nil {
+		copied := *c.HttpClient
+		httpClient = &copied
+	}
+	transport, err := withBaseTransport(httpClient.Transport, config)
+	if err != nil {
+		return err
+	}
+	httpClient.Transport = transport
+	c.HttpClient = httpClient
+
+	return nil
+}
+
+// SPKIPin returns the pin of a certificate's public key, for use in TLSOptions.
+func SPKIPin(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return spkiPinPrefix + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// validateSPKIPin checks that a pin is a base64 SHA-256 digest with the pin prefix.
+func validateSPKIPin(pin string) error {
+	digest, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(pin, spkiPinPrefix))
+	if !strings.HasPrefix(pin, spkiPinPrefix) || err != nil || len(digest) != sha256.Size {
+		return fmt.Errorf("invalid SPKI pin %q: want %s<base64 SHA-256 digest>", pin, spkiPinPrefix)
+	}
+	return nil
+}
+
+// verifySPKIPins checks that a certificate in the verified chains matches one of the expected pins.
+func verifySPKIPins(state tls.ConnectionState, expected []string) error {
+	var presented []string
+	seen := make(map[string]bool)
+	for _, chain := range state.VerifiedChains {
+		for _, cert := range chain {
+			pin := SPKIPin(cert)
+			for _, want := range expected {
+				if pin == want {
+					return nil
+				}
+			}
+			if !seen[pin] {
+				seen[pin] = true
+				presented = append(presented, pin)
+			}
+		}
+	}
+
+	return &PinMismatchError{ServerName: state.ServerName, Presented: presented, Expected: expected}
+}
+
+// withBaseTransport applies a TLS configuration to the innermost transport of a chain built by
+// UseTokenSource and UseRequestSigning. An *http.Transport is cloned so that its proxy, timeout
+// and pooling settings are kept; other transports cannot be rewrapped and are reported as errors.
+func withBaseTransport(transport http.RoundTripper, config *tls.Config) (http.RoundTripper, error) {
+	switch t := transport.(type) {
+	case nil:
+		base := http.DefaultTransport.(*http.Transport).Clone()
+		base.TLSClientConfig = config
+		return base, nil
+	case *http.Transport:
+		base := t.Clone()
+		base.TLSClientConfig = config
+		return base, nil
+	case *oauth2.Transport:
+		base, err := withBaseTransport(t.Base, config)
+		if err != nil {
+			return nil, err
+		}
+		return &oauth2.Transport{Source: t.Source, Base: base}, nil
+	case *SigningTransport:
+		base, err := withBaseTransport(t.Base, config)
+		if err != nil {
+			return nil, err
+		}
+		copied := *t
+		copied.Base = base
+		return &copied, nil
+	default:
+		return nil, fmt.Errorf("unable to apply TLS options to transport of type %T", transport)
+	}
+}
+
+// certificateReloader serves a client certificate from files, reloading it when they change.
+type certificateReloader struct {
+	certFile string
+	keyFile  string
+
+	mu          sync.Mutex
+	cert        *tls.Certificate
+	certModTime time.Time
+	keyModTime  time.Time
+}
+
+// newCertificateReloader loads the certificate, failing if it cannot be used.
+func newCertificateReloader(certFile, keyFile string) (*certificateReloader, error) {
+	r := &certificateReloader{certFile: certFile, keyFile: keyFile}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// getClientCertificate implements tls.Config.GetClientCertificate. A certificate that fails to
+// reload is logged and the previous one is kept, so a half-written renewal does not break connections.
+func (r *certificateReloader) getClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	if err := r.reload(); err != nil {
+		log.Printf("client certificate: %v; keeping the previous certificate", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cert, nil
+}
+
+// reload loads the certificate if either file changed since it was last loaded.
+func (r *certificateReloader) reload() error {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return fmt.Errorf("unable to stat client certificate: %w", err)
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return fmt.Errorf("unable to stat client key: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cert != nil && certInfo.ModTime().Equal(r.certModTime) && keyInfo.ModTime().Equal(r.keyModTime) {
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("unable to load client certificate: %w", err)
+	}
+	r.cert = &cert
+	r.certModTime = certInfo.ModTime()
+	r.keyModTime = keyInfo.ModTime()
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package accountslib
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// testPin returns a well-formed pin that matches no real key.
+func testPin(b byte) string {
+	return spkiPinPrefix + base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, sha256.Size))
+}
+
+func TestTLSPinning(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	// Rejected handshakes are expected; keep them out of the test output
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	serverPin := SPKIPin(server.Certificate())
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+
+	tests := []struct {
+		name       string
+		pins       []string
+		backupPins []string
+		// mismatch is whether the connection must fail with a PinMismatchError.
+		mismatch bool
+	}{
+		{name: "no pins"},
+		{name: "pin matches", pins: []string{serverPin}, backupPins: []string{testPin(1)}},
+		{name: "backup pin matches", pins: []string{testPin(1)}, backupPins: []string{serverPin}},
+		{name: "no pin matches", pins: []string{testPin(1)}, backupPins: []string{testPin(2)}, mismatch: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewTLSHTTPClient(TLSOptions{RootCAs: roots, Pins: tt.pins, BackupPins: tt.backupPins})
+			if err != nil {
+				t.Fatalf("NewTLSHTTPClient returned error: %v", err)
+			}
+
+			res, err := client.Get(server.URL)
+			if !tt.mismatch {
+				if err != nil {
+					t.Fatalf("request failed: %v", err)
+				}
+				res.Body.Close()
+				return
+			}
+
+			var mismatch *PinMismatchError
+			if !errors.As(err, &mismatch) {
+				t.Fatalf("expected a PinMismatchError, got %v", err)
+			}
+			if len(mismatch.Presented) != 1 || mismatch.Presented[0] != serverPin {
+				t.Errorf("presented pins %v, want [%s]", mismatch.Presented, serverPin)
+			}
+			want := append(append([]string(nil), tt.pins...), tt.backupPins...)
+			if strings.Join(mismatch.Expected, ",") != strings.Join(want, ",") {
+				t.Errorf("expected pins %v, want %v", mismatch.Expected, want)
+			}
+			if !strings.Contains(mismatch.Error(), serverPin) {
+				t.Errorf("error %q does not name the presented pin", mismatch.Error())
+			}
+		})
+	}
+}
+
+func TestNewTLSConfigRejectsPins(t *testing.T) {
+	tests := []struct {
+		name string
+		opts TLSOptions
+	}{
+		{name: "pins without backup", opts: TLSOptions{Pins: []string{testPin(1)}}},
+		{name: "backup without pins", opts: TLSOptions{BackupPins: []string{testPin(1)}}},
+		{name: "missing prefix", opts: TLSOptions{Pins: []string{strings.TrimPrefix(testPin(1), spkiPinPrefix)}, BackupPins: []string{testPin(2)}}},
+		{name: "short digest", opts: TLSOptions{Pins: []string{spkiPinPrefix + "AAAA"}, BackupPins: []string{testPin(2)}}},
+		{name: "invalid backup", opts: TLSOptions{Pins: []string{testPin(1)}, BackupPins: []string{"sha256/not base64"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewTLSConfig(tt.opts); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package accountslib
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Clock is the time source of background workers, replaceable in tests.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// SystemClock is the Clock backed by the time package.
+var SystemClock Clock = systemClock{}
+
+// TokenJanitorOptions configures a TokenJanitor. Zero fields take the defaults described on each field.
+type TokenJanitorOptions struct {
+	// Interval is the time between runs. Defaults to an hour.
+	Interval time.Duration
+	// Jitter adds a random delay of up to this much to every interval, so that replicas started
+	// together do not all call the server at once. Defaults to a tenth of Interval; negative
+	// values disable it.
+	Jitter time.Duration
+	// Leader, when set, is called before every run, and the run is skipped unless it returns
+	// true. Back it with a lease or an advisory lock so that only one replica runs at a time.
+	Leader func(ctx context.Context) (bool, error)
+	// OnRun, when set, is called with the outcome of every run.
+	OnRun func(TokenJanitorRun)
+	// Clock defaults to SystemClock.
+	Clock Clock
+}
+
+func (o TokenJanitorOptions) withDefaults() TokenJanitorOptions {
+	if o.Interval <= 0 {
+		o.Interval = time.Hour
+	}
+	if o.Jitter == 0 {
+		o.Jitter = o.Interval / 10
+	}
+	if o.Clock == nil {
+		o.Clock = SystemClock
+	}
+	return o
+}
+
+// TokenJanitorRun is the outcome of one run of a TokenJanitor.
+type TokenJanitorRun struct {
+	StartedAt time.Time
+	// Skipped is set when this instance was not the leader.
+	Skipped bool
+	// Deleted is the number of expired tokens deleted.
+	Deleted int
+	Err     error
+}
+
+// TokenJanitorStats are the totals of every run of a TokenJanitor so far.
+type TokenJanitorStats struct {
+	Runs     int
+	Skipped  int
+	Failures int
+	Deleted  int
+	LastRun  TokenJanitorRun
+}
+
+// TokenJanitor deletes expired tokens on a schedule. It is safe for concurrent use.
+type TokenJanitor struct {
+	client  *Client
+	options TokenJanitorOptions
+
+	mu    sync.Mutex
+	stats TokenJanitorStats
+}
+
+// NewTokenJanitor returns a janitor for the client's tokens. Call Run to start it.
+func (c *Client) NewTokenJanitor(opts ...TokenJanitorOptions) *TokenJanitor {
+	var options TokenJanitorOptions
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+	return &TokenJanitor{client: c, options: options.withDefaults()}
+}
+
+// Run deletes expired tokens every interval until ctx is cancelled, which also cancels a run in
+// progress. It blocks, so it is usually started in its own goroutine.
+func (j *TokenJanitor) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-j.options.Clock.After(j.nextDelay()):
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+		j.RunOnce(ctx)
+	}
+}
+
+// RunOnce deletes expired tokens now, unless the Leader hook says another instance should.
+func (j *TokenJanitor) RunOnce(ctx context.Context) TokenJanitorRun {
+	run := TokenJanitorRun{StartedAt: j.options.Clock.Now()}
+
+	leader := true
+	if j.options.Leader != nil {
+		var err error
+		leader, err = j.options.Leader(ctx)
+		if err != nil {
+			run.Err = fmt.Errorf("leader election failed: %w", err)
+		}
+	}
+
+	switch {
+	case run.Err != nil:
+	case !leader:
+		run.Skipped = true
+	default:
+		run.Deleted, run.Err = j.client.PurgeExpiredTokens(ctx)
+	}
+	if run.Err != nil && ctx.Err() == nil {
+		log.Printf("token janitor: %v", run.Err)
+	}
+
+	j.mu.Lock()
+	j.stats.Runs++
+	j.stats.Deleted += run.Deleted
+	if run.Skipped {
+		j.stats.Skipped++
+	}
+	if run.Err != nil {
+		j.stats.Failures++
+	}
+	j.stats.LastRun = run
+	j.mu.Unlock()
+
+	if j.options.OnRun != nil {
+		j.options.OnRun(run)
+	}
+
+	return run
+}
+
+// Stats returns the totals of every run so far.
+func (j *TokenJanitor) Stats() TokenJanitorStats {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.stats
+}
+
+// nextDelay returns the interval plus a random share of the jitter.
+func (j *TokenJanitor) nextDelay() time.Duration {
+	delay := j.options.Interval
+	if j.options.Jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(j.options.Jitter)))
+	}
+	return delay
+}
@@ -0,0 +1,279 @@
+package accountslib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock whose timers fire only when the test says so.
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	delays chan time.Duration
+	fire   chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{
+		now:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		delays: make(chan time.Duration, 16),
+		fire:   make(chan time.Time),
+	}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// After records the requested delay; the returned channel fires on the next call to Advance.
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.delays <- d
+	return c.fire
+}
+
+// Advance waits for a timer to be set, moves the clock past it and fires it.
+func (c *fakeClock) Advance(t *testing.T) time.Duration {
+	t.Helper()
+	d := c.waitForTimer(t)
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	now := c.now
+	c.mu.Unlock()
+	c.fire <- now
+	return d
+}
+
+func (c *fakeClock) waitForTimer(t *testing.T) time.Duration {
+	t.Helper()
+	select {
+	case d := <-c.delays:
+		return d
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a timer")
+		return 0
+	}
+}
+
+// newPurgeServer returns a server answering the purge endpoint with deleted tokens, and a count of its calls.
+func newPurgeServer(t *testing.T, deleted int) (*httptest.Server, func() int) {
+	var mu sync.Mutex
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete || r.URL.Path != "/api/tokens/expired" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"deleted":%d}`, deleted)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return calls
+	}
+}
+
+func TestTokenJanitorRunOnce(t *testing.T) {
+	server, calls := newPurgeServer(t, 3)
+	clock := newFakeClock()
+
+	var reported []TokenJanitorRun
+	janitor := NewClient(server.URL, "token", "key").NewTokenJanitor(TokenJanitorOptions{
+		Clock: clock,
+		OnRun: func(run TokenJanitorRun) { reported = append(reported, run) },
+	})
+
+	run := janitor.RunOnce(context.Background())
+	if run.Err != nil {
+		t.Fatalf("RunOnce returned error: %v", run.Err)
+	}
+	if run.Deleted != 3 || run.Skipped || !run.StartedAt.Equal(clock.Now()) {
+		t.Errorf("unexpected run: %+v", run)
+	}
+	if calls() != 1 {
+		t.Errorf("expected 1 purge call, got %d", calls())
+	}
+	if len(reported) != 1 || reported[0] != run {
+		t.Errorf("OnRun got %+v, want [%+v]", reported, run)
+	}
+}
+
+func TestTokenJanitorLeaderSkipsPurge(t *testing.T) {
+	server, calls := newPurgeServer(t, 3)
+
+	leader := false
+	janitor := NewClient(server.URL, "token", "key").NewTokenJanitor(TokenJanitorOptions{
+		Clock:  newFakeClock(),
+		Leader: func(context.Context) (bool, error) { return leader, nil },
+	})
+
+	if run := janitor.RunOnce(context.Background()); !run.Skipped || run.Err != nil || run.Deleted != 0 {
+		t.Errorf("expected a skipped run, got %+v", run)
+	}
+	if calls() != 0 {
+		t.Errorf("expected no purge calls while not the leader, got %d", calls())
+	}
+
+	leader = true
+	if run := janitor.RunOnce(context.Background()); run.Skipped || run.Deleted != 3 {
+		t.Errorf("expected a purge as leader, got %+v", run)
+	}
+	if calls() != 1 {
+		t.Errorf("expected 1 purge call as leader, got %d", calls())
+	}
+}
+
+func TestTokenJanitorLeaderError(t *testing.T) {
+	server, calls := newPurgeServer(t, 3)
+
+	errLease := errors.New("lease unavailable")
+	janitor := NewClient(server.URL, "token", "key").NewTokenJanitor(TokenJanitorOptions{
+		Clock:  newFakeClock(),
+		Leader: func(context.Context) (bool, error) { return true, errLease },
+	})
+
+	run := janitor.RunOnce(context.Background())
+	if !errors.Is(run.Err, errLease) || run.Skipped {
+		t.Errorf("expected the leader error, got %+v", run)
+	}
+	if calls() != 0 {
+		t.Errorf("expected no purge calls after a leader error, got %d", calls())
+	}
+}
+
+func TestTokenJanitorStats(t *testing.T) {
+	server, _ := newPurgeServer(t, 2)
+
+	leader := true
+	janitor := NewClient(server.URL, "token", "key").NewTokenJanitor(TokenJanitorOptions{
+		Clock:  newFakeClock(),
+		Leader: func(context.Context) (bool, error) { return leader, nil },
+	})
+
+	janitor.RunOnce(context.Background())
+	janitor.RunOnce(context.Background())
+	leader = false
+	janitor.RunOnce(context.Background())
+
+	// A client without an API key fails validation before reaching the server
+	janitor.client.ApiKey = ""
+	leader = true
+	failed := janitor.RunOnce(context.Background())
+
+	stats := janitor.Stats()
+	want := TokenJanitorStats{Runs: 4, Skipped: 1, Failures: 1, Deleted: 4, LastRun: failed}
+	if stats != want {
+		t.Errorf("got stats %+v, want %+v", stats, want)
+	}
+}
+
+func TestTokenJanitorRunInterval(t *testing.T) {
+	server, calls := newPurgeServer(t, 1)
+	clock := newFakeClock()
+
+	janitor := NewClient(server.URL, "token", "key").NewTokenJanitor(TokenJanitorOptions{
+		Interval: time.Minute,
+		Jitter:   -1,
+		Clock:    clock,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		janitor.Run(ctx)
+		close(done)
+	}()
+
+	// Run waits a full interval before the first purge, and between purges
+	for i := 1; i <= 3; i++ {
+		if d := clock.Advance(t); d != time.Minute {
+			t.Errorf("run %d: waited %v, want %v", i, d, time.Minute)
+		}
+	}
+	clock.waitForTimer(t)
+	if calls() != 3 {
+		t.Errorf("expected 3 purge calls, got %d", calls())
+	}
+	if stats := janitor.Stats(); stats.Runs != 3 || stats.Deleted != 3 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after ctx was cancelled")
+	}
+	if calls() != 3 {
+		t.Errorf("expected no purge after cancellation, got %d calls", calls())
+	}
+}
+
+func TestTokenJanitorJitter(t *testing.T) {
+	interval := time.Hour
+
+	janitor := NewClient("https://accounts.example.com", "token", "key").NewTokenJanitor(TokenJanitorOptions{Interval: interval})
+	if janitor.options.Jitter != interval/10 {
+		t.Fatalf("expected default jitter %v, got %v", interval/10, janitor.options.Jitter)
+	}
+	varied := false
+	for i := 0; i < 100; i++ {
+		d := janitor.nextDelay()
+		if d < interval || d >= interval+interval/10 {
+			t.Fatalf("delay %v outside [%v, %v)", d, interval, interval+interval/10)
+		}
+		if d != interval {
+			varied = true
+		}
+	}
+	if !varied {
+		t.Error("expected jitter to vary the delay")
+	}
+
+	janitor = NewClient("https://accounts.example.com", "token", "key").NewTokenJanitor(TokenJanitorOptions{Interval: interval, Jitter: -1})
+	for i := 0; i < 10; i++ {
+		if d := janitor.nextDelay(); d != interval {
+			t.Fatalf("expected %v with jitter disabled, got %v", interval, d)
+		}
+	}
+}
+
+func TestTokenJanitorRunStopsWhileWaiting(t *testing.T) {
+	server, calls := newPurgeServer(t, 1)
+	clock := newFakeClock()
+
+	janitor := NewClient(server.URL, "token", "key").NewTokenJanitor(TokenJanitorOptions{Clock: clock})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		janitor.Run(ctx)
+		close(done)
+	}()
+
+	clock.waitForTimer(t)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after ctx was cancelled")
+	}
+	if calls() != 0 || janitor.Stats().Runs != 0 {
+		t.Errorf("expected no runs, got %d calls and stats %+v", calls(), janitor.Stats())
+	}
+}
@@ -0,0 +1,150 @@
+package accountslib
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// TokenScope names what a token may be used for.
+type TokenScope string
+
+// Token scopes registered by DefaultTokenScopes.
+const (
+	ScopeActivation     TokenScope = "activation"
+	ScopeAuthentication TokenScope = "authentication"
+	ScopePasswordReset  TokenScope = "password-reset"
+	ScopeAPI            TokenScope = "api"
+	ScopeEmailChange    TokenScope = "email-change"
+	ScopeRefresh        TokenScope = "refresh"
+)
+
+// ErrUnknownTokenScope is returned for scopes missing from the client's token scope registry.
+var ErrUnknownTokenScope = errors.New("unknown token scope")
+
+// TokenScopeDefinition describes the tokens issued for a scope.
+type TokenScopeDefinition struct {
+	Scope TokenScope
+	// Lifetime is how long a new token is valid.
+	Lifetime time.Duration
+	// SingleUse tokens are deleted when they are redeemed, for example by CompletePasswordReset
+	// and ConfirmEmailChange. The flag is stored with each token so the server's own redemption
+	// endpoints can do the same. Refresh tokens are rotated on every use whatever the flag says.
+	SingleUse bool
+	// Audiences lists the audiences a token may be issued for. When it is empty the scope
+	// takes no audience.
+	Audiences []string
+	// RefreshFamilyLifetime bounds how long the refresh tokens issued with access tokens of this
+	// scope can be rotated, counted from the login that started the family. Rotation never
+	// extends a family past it. Zero means the Lifetime of ScopeRefresh.
+	RefreshFamilyLifetime time.Duration
+}
+
+// allowsAudience reports whether a token of this scope may be issued for audience.
+func (d TokenScopeDefinition) allowsAudience(audience string) bool {
+	if len(d.Audiences) == 0 {
+		return audience == ""
+	}
+	for _, allowed := range d.Audiences {
+		if allowed == audience {
+			return true
+		}
+	}
+	return false
+}
+
+// TokenScopeRegistry holds the token scopes a client may issue. It is safe for concurrent use.
+type TokenScopeRegistry struct {
+	mu     sync.RWMutex
+	scopes map[TokenScope]TokenScopeDefinition
+}
+
+// NewTokenScopeRegistry returns a registry holding the given definitions.
+func NewTokenScopeRegistry(definitions ...TokenScopeDefinition) (*TokenScopeRegistry, error) {
+	registry := &TokenScopeRegistry{scopes: make(map[TokenScope]TokenScopeDefinition)}
+	for _, definition := range definitions {
+		if err := registry.Register(definition); err != nil {
+			return nil, err
+		}
+	}
+	return registry, nil
+}
+
+// Register adds a scope, replacing any earlier definition with the same name.
+func (r *TokenScopeRegistry) Register(definition TokenScopeDefinition) error {
+	if definition.Scope == "" {
+		return errors.New("scope is required")
+	}
+	if definition.Lifetime <= 0 {
+		return fmt.Errorf("scope %q: lifetime must be positive", definition.Scope)
+	}
+	if definition.RefreshFamilyLifetime < 0 {
+		return fmt.Errorf("scope %q: refresh family lifetime must not be negative", definition.Scope)
+	}
+	definition.Audiences = append([]string(nil), definition.Audiences...)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.scopes[definition.Scope] = definition
+	return nil
+}
+
+// Lookup returns the definition of a scope.
+func (r *TokenScopeRegistry) Lookup(scope TokenScope) (TokenScopeDefinition, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	definition, ok := r.scopes[scope]
+	return definition, ok
+}
+
+// Scopes returns every registered definition, ordered by scope.
+func (r *TokenScopeRegistry) Scopes() []TokenScopeDefinition {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	definitions := make([]TokenScopeDefinition, 0, len(r.scopes))
+	for _, definition := range r.scopes {
+		definitions = append(definitions, definition)
+	}
+	sort.Slice(definitions, func(i, j int) bool {
+		return definitions[i].Scope < definitions[j].Scope
+	})
+	return definitions
+}
+
+// DefaultTokenScopes returns a new registry holding the built-in scopes. Clients without a
+// TokenScopes registry of their own use these definitions; callers may register more scopes on
+// the returned registry without affecting other clients.
+func DefaultTokenScopes() *TokenScopeRegistry {
+	return &TokenScopeRegistry{scopes: map[TokenScope]TokenScopeDefinition{
+		ScopeActivation:     {Scope: ScopeActivation, Lifetime: 3 * 24 * time.Hour, SingleUse: true},
+		ScopeAuthentication: {Scope: ScopeAuthentication, Lifetime: 24 * time.Hour},
+		ScopePasswordReset:  {Scope: ScopePasswordReset, Lifetime: time.Hour, SingleUse: true},
+		ScopeAPI:            {Scope: ScopeAPI, Lifetime: 90 * 24 * time.Hour},
+		ScopeEmailChange:    {Scope: ScopeEmailChange, Lifetime: 24 * time.Hour, SingleUse: true},
+		ScopeRefresh:        {Scope: ScopeRefresh, Lifetime: 30 * 24 * time.Hour, SingleUse: true},
+	}}
+}
+
+// defaultTokenScopes is the registry of clients without one of their own. It is never
+// exposed, so the built-in definitions cannot be changed from outside the package.
+var defaultTokenScopes = DefaultTokenScopes()
+
+// tokenScopes returns the client's token scope registry, or the built-in scopes.
+func (c *Client) tokenScopes() *TokenScopeRegistry {
+	if c.TokenScopes != nil {
+		return c.TokenScopes
+	}
+	return defaultTokenScopes
+}
+
+// tokenScope returns the definition of a scope from the client's registry.
+func (c *Client) tokenScope(scope TokenScope) (TokenScopeDefinition, error) {
+	definition, ok := c.tokenScopes().Lookup(scope)
+	if !ok {
+		return TokenScopeDefinition{}, fmt.Errorf("%w: %q", ErrUnknownTokenScope, scope)
+	}
+	return definition, nil
+}
@@ -2,6 +2,9 @@ package accountslib
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -17,13 +20,22 @@ import (
 )
 
 // Token represents the structure of a token.
+//
+// On the wire a token is a JSON object with the fields id, user_id, expiry, scope, audience
+// and single_use. The server includes plaintext only in its response to CreateToken; it stores
+// the SHA-256 hash of the plaintext and looks tokens up by that hash, so neither the plaintext
+// nor the hash is returned afterwards. Hash is filled in locally from the plaintext or the hash
+// a token was looked up by.
 type Token struct {
-	Plaintext string
-	Hash      []byte
-	UserID    uuid.UUID
-	Expiry    time.Time
-	Scope     string
-	Error     error
+	ID        uuid.UUID  `json:"id"`
+	Plaintext string     `json:"plaintext,omitempty"`
+	Hash      []byte     `json:"-"`
+	UserID    uuid.UUID  `json:"user_id"`
+	Expiry    time.Time  `json:"expiry"`
+	Scope     TokenScope `json:"scope"`
+	Audience  string     `json:"audience,omitempty"`
+	SingleUse bool       `json:"single_use,omitempty"`
+	Error     error      `json:"-"`
 }
 
 // GetTokenByPlaintextInput represents the input data for retrieving a token by plaintext.
@@ -31,10 +43,17 @@ type GetTokenByPlaintextInput struct {
 	Plaintext string `json:"plaintext"`
 }
 
+// GetTokenByHashInput represents the input data for retrieving a token by the hash of its plaintext.
+// The hash is sent hex encoded, like every other hash the API takes.
+type GetTokenByHashInput struct {
+	Hash []byte
+}
+
 // CreateTokenInput represents the input data for creating a new token.
 type CreateTokenInput struct {
-	UserID uuid.UUID `json:"user_id"`
-	Scope  string    `json:"scope"`
+	UserID   uuid.UUID  `json:"user_id"`
+	Scope    TokenScope `json:"scope"`
+	Audience string     `json:"audience,omitempty"`
 }
 
 // GetTokensByUserIDInput represents the input data for retrieving a token by user ID.
@@ -44,7 +63,7 @@ type GetTokensByUserIDInput struct {
 
 // GetTokensByScopeInput represents the input data for retrieving a token by scope.
 type GetTokensByScopeInput struct {
-	Scope string `json:"scope"`
+	Scope TokenScope `json:"scope"`
 }
 
 // DeleteTokenInput represents the input data for deleting a token.
@@ -71,17 +90,30 @@ func (t *Token) Validate() error {
 	return nil
 }
 
-// CreateToken creates a new token for a user and returns it.
+// CreateToken creates a new token for a user and returns it. The scope must be registered in
+// the client's TokenScopes, which sets the token's lifetime and the audiences it may have.
 func (c *Client) CreateToken(input CreateTokenInput) (*Token, error) {
+	// Look up the scope
+	scope, err := c.tokenScope(input.Scope)
+	if err != nil {
+		return nil, err
+	}
+	if !scope.allowsAudience(input.Audience) {
+		return nil, fmt.Errorf("audience %q is not allowed for scope %q", input.Audience, input.Scope)
+	}
+
 	// Create the payload
 	payload := Token{
-		UserID: input.UserID,
-		Scope:  input.Scope,
-		Expiry: time.Now().Add(time.Hour * 24), // token expires after 24 hours
+		ID:        uuid.New(),
+		UserID:    input.UserID,
+		Scope:     input.Scope,
+		Audience:  input.Audience,
+		SingleUse: scope.SingleUse,
+		Expiry:    time.Now().Add(scope.Lifetime),
 	}
 
 	// Validate the payload
-	err := payload.Validate()
+	err = payload.Validate()
 	if err != nil {
 		return nil, err
 	}
@@ -121,13 +153,37 @@ func (c *Client) CreateToken(input CreateTokenInput) (*Token, error) {
 	if err := json.NewDecoder(res.Body).Decode(&createdToken); err != nil {
 		return nil, fmt.Errorf("failed to decode response body: %w", err)
 	}
+	if createdToken.Plaintext == "" {
+		return nil, errors.New("server did not return the token plaintext")
+	}
+	createdToken.Hash = HashToken(createdToken.Plaintext)
 
 	return &createdToken, nil
 }
 
+// GetTokenByPlaintext gets a token by its plaintext. Only the hash of the plaintext is sent to the server.
 func (c *Client) GetTokenByPlaintext(input GetTokenByPlaintextInput) (*Token, error) {
+	if input.Plaintext == "" {
+		return nil, errors.New("plaintext is required")
+	}
+
+	return c.GetTokenByHash(GetTokenByHashInput{Hash: HashToken(input.Plaintext)})
+}
+
+// GetTokenByHash gets a token by the SHA-256 hash of its plaintext, as returned by HashToken.
+func (c *Client) GetTokenByHash(input GetTokenByHashInput) (*Token, error) {
+	if len(input.Hash) != sha256.Size {
+		return nil, errors.New("hash must be a SHA-256 digest")
+	}
+
+	// Marshal the payload
+	jsonPayload, err := json.Marshal(map[string]string{"token_hash": hex.EncodeToString(input.Hash)})
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal payload: %w", err)
+	}
+
 	// Create the new HTTP request
-	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/api/tokens/%s", c.BaseURL, url.PathEscape(input.Plaintext)), nil)
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/api/tokens/lookup", c.BaseURL), bytes.NewBuffer(jsonPayload))
 	if err != nil {
 		return nil, fmt.Errorf("unable to create new request: %w", err)
 	}
@@ -155,10 +211,26 @@ func (c *Client) GetTokenByPlaintext(input GetTokenByPlaintextInput) (*Token, er
 	if err := json.NewDecoder(res.Body).Decode(token); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
+	token.Hash = input.Hash
 
 	return token, nil
 }
 
+// HashToken returns the SHA-256 digest the server stores and looks tokens up by in place of their
+// plaintext. Requests carry it hex encoded.
+func HashToken(plaintext string) []byte {
+	sum := sha256.Sum256([]byte(plaintext))
+	return sum[:]
+}
+
+// hashSecret returns the hex SHA-256 digest of a secret. Session tokens, refresh tokens, API
+// keys, service account secrets and one-time codes are all stored by the server as this digest
+// and looked up by it, so their plaintext never leaves the client after it is issued.
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
 // GetTokensByUserID gets all tokens associated with a user ID.
 func (c *Client) GetTokensByUserID(input GetTokensByUserIDInput) ([]Token, error) {
 	// Create a new HTTP request
@@ -203,8 +275,13 @@ func (c *Client) GetTokensByUserID(input GetTokensByUserIDInput) ([]Token, error
 
 // GetTokensByScope gets all tokens associated with a scope.
 func (c *Client) GetTokensByScope(input GetTokensByScopeInput) ([]Token, error) {
+	// Validate the scope
+	if _, err := c.tokenScope(input.Scope); err != nil {
+		return nil, err
+	}
+
 	// Create a new HTTP request
-	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/api/tokens/scope/%s", c.BaseURL, url.PathEscape(input.Scope)), nil)
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/api/tokens/scope/%s", c.BaseURL, url.PathEscape(string(input.Scope))), nil)
 	if err != nil {
 		return nil, fmt.Errorf("unable to create new request: %w", err)
 	}
@@ -283,6 +360,23 @@ func (c *Client) DeleteToken(input DeleteTokenInput) error {
 	return nil
 }
 
+// redeemToken deletes a token of a SingleUse scope once it has been accepted, so that it
+// cannot be presented again. Other tokens are left alone. The client's registry decides, as the
+// server omits the token's own flag when it is false.
+func (c *Client) redeemToken(token *Token) error {
+	definition, err := c.tokenScope(token.Scope)
+	if err != nil {
+		return err
+	}
+	if !definition.SingleUse {
+		return nil
+	}
+	if err := c.DeleteToken(DeleteTokenInput{UserID: token.UserID, TokenID: token.ID}); err != nil {
+		return fmt.Errorf("unable to redeem token: %w", err)
+	}
+	return nil
+}
+
 // DeleteTokensByUserID sends a request to the server to delete all tokens for the given user ID.
 func (c *Client) DeleteTokensByUserID(input DeleteTokensByUserIDInput) error {
 	// Create a new HTTP request
@@ -312,26 +406,39 @@ func (c *Client) DeleteTokensByUserID(input DeleteTokensByUserIDInput) error {
 	return nil
 }
 
-// Validate validates the Client fields.
+// Validate validates the Client fields. Token is not required when a token source authenticates the client.
 func (c *Client) Validate() error {
+	var tokenRules []validation.Rule
+	if !c.usesTokenSource() {
+		tokenRules = append(tokenRules, validation.Required)
+	}
+
 	return validation.ValidateStruct(c,
-		validation.Field(&c.Token, validation.Required),
+		validation.Field(&c.Token, tokenRules...),
 		validation.Field(&c.ApiKey, validation.Required),
 		validation.Field(&c.BaseURL, validation.Required, is.URL),
 	)
 }
 
+// DeleteExpiredTokens deletes every token past its expiry. TokenJanitor runs it on a schedule.
 func (c *Client) DeleteExpiredTokens() error {
+	_, err := c.PurgeExpiredTokens(context.Background())
+	return err
+}
+
+// PurgeExpiredTokens deletes every token past its expiry and returns how many were deleted.
+// Servers that do not report a count yield zero.
+func (c *Client) PurgeExpiredTokens(ctx context.Context) (int, error) {
 	// Validate the client before proceeding
 	err := c.Validate()
 	if err != nil {
-		return fmt.Errorf("client validation failed: %w", err)
+		return 0, fmt.Errorf("client validation failed: %w", err)
 	}
 
 	// Create a new HTTP request
-	req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("%s/api/tokens/expired", c.BaseURL), nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, fmt.Sprintf("%s/api/tokens/expired", c.BaseURL), nil)
 	if err != nil {
-		return fmt.Errorf("unable to create new request: %w", err)
+		return 0, fmt.Errorf("unable to create new request: %w", err)
 	}
 
 	// Set the appropriate headers
@@ -342,17 +449,32 @@ func (c *Client) DeleteExpiredTokens() error {
 	// Send the HTTP request
 	res, err := c.HttpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("unable to send request: %w", err)
+		return 0, fmt.Errorf("unable to send request: %w", err)
 	}
 	defer res.Body.Close()
 
 	// Check the status code
 	if res.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(res.Body)
-		return fmt.Errorf("unexpected status code: got %v, body: %s", res.StatusCode, body)
+		return 0, fmt.Errorf("unexpected status code: got %v, body: %s", res.StatusCode, body)
 	}
 
-	return nil
+	// Decode the response body
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return 0, fmt.Errorf("unable to read response body: %w", err)
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return 0, nil
+	}
+	var result struct {
+		Deleted int `json:"deleted"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return 0, fmt.Errorf("unable to decode response body: %w", err)
+	}
+
+	return result.Deleted, nil
 }
 
 func (c *Client) VerifyToken(token string) (*Token, error) {
@@ -363,8 +485,9 @@ func (c *Client) VerifyToken(token string) (*Token, error) {
 	}
 
 	// Prepare the payload
+	hash := HashToken(token)
 	tokenPayload := map[string]string{
-		"token": token,
+		"token_hash": hex.EncodeToString(hash),
 	}
 
 	jsonPayload, err := json.Marshal(tokenPayload)
@@ -402,6 +525,7 @@ func (c *Client) VerifyToken(token string) (*Token, error) {
 	if err != nil {
 		return nil, fmt.Errorf("error decoding response body: %w", err)
 	}
+	verifiedToken.Hash = hash
 
 	return &verifiedToken, nil
 }
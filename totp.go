@@ -0,0 +1,345 @@
+package accountslib
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// TOTPOptions configures RFC 6238 code generation and validation.
+type TOTPOptions struct {
+	// Digits is the length of a code. Defaults to 6.
+	Digits int
+	// Period is how long a code stays current. Defaults to 30 seconds.
+	Period time.Duration
+	// Skew is the number of periods before and after the current one that are
+	// also accepted, to allow for clock drift. Zero accepts the current period only.
+	Skew uint
+}
+
+// DefaultTOTPOptions are the options used by authenticator apps unless told otherwise.
+var DefaultTOTPOptions = TOTPOptions{Digits: 6, Period: 30 * time.Second, Skew: 1}
+
+// recoveryCodeCount is the number of recovery codes issued at a time.
+const recoveryCodeCount = 10
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func (o TOTPOptions) withDefaults() (TOTPOptions, error) {
+	if o.Digits == 0 {
+		o.Digits = DefaultTOTPOptions.Digits
+	}
+	if o.Period == 0 {
+		o.Period = DefaultTOTPOptions.Period
+	}
+	if o.Digits < 6 || o.Digits > 8 {
+		return o, errors.New("TOTP codes must have 6 to 8 digits")
+	}
+	if o.Period < time.Second {
+		return o, errors.New("TOTP period must be at least one second")
+	}
+	return o, nil
+}
+
+// GenerateTOTPSecret returns a new random 160 bit secret, base32 encoded without padding.
+func GenerateTOTPSecret() (string, error) {
+	secret := make([]byte, 20)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("unable to generate secret: %w", err)
+	}
+	return totpEncoding.EncodeToString(secret), nil
+}
+
+// GenerateTOTPCode returns the code for secret at time t.
+func GenerateTOTPCode(secret string, t time.Time, opts TOTPOptions) (string, error) {
+	opts, err := opts.withDefaults()
+	if err != nil {
+		return "", err
+	}
+
+	key, err := decodeTOTPSecret(secret)
+	if err != nil {
+		return "", err
+	}
+
+	return totpCode(key, uint64(t.Unix())/uint64(opts.Period/time.Second), opts.Digits), nil
+}
+
+// ValidateTOTPCode reports whether code is valid for secret at time t, accepting
+// codes from opts.Skew periods on either side of t.
+func ValidateTOTPCode(secret, code string, t time.Time, opts TOTPOptions) (bool, error) {
+	opts, err := opts.withDefaults()
+	if err != nil {
+		return false, err
+	}
+
+	key, err := decodeTOTPSecret(secret)
+	if err != nil {
+		return false, err
+	}
+
+	code = strings.TrimSpace(code)
+	if len(code) != opts.Digits {
+		return false, nil
+	}
+
+	counter := uint64(t.Unix()) / uint64(opts.Period/time.Second)
+	valid := false
+	for offset := -int64(opts.Skew); offset <= int64(opts.Skew); offset++ {
+		if int64(counter)+offset < 0 {
+			continue
+		}
+		candidate := totpCode(key, uint64(int64(counter)+offset), opts.Digits)
+		// Compare every candidate so timing does not reveal which window matched
+		if subtle.ConstantTimeCompare([]byte(candidate), []byte(code)) == 1 {
+			valid = true
+		}
+	}
+
+	return valid, nil
+}
+
+// TOTPKeyURI returns the otpauth:// URI authenticator apps use to enroll secret.
+func TOTPKeyURI(issuer, accountName, secret string, opts TOTPOptions) string {
+	if opts.Digits == 0 {
+		opts.Digits = DefaultTOTPOptions.Digits
+	}
+	if opts.Period == 0 {
+		opts.Period = DefaultTOTPOptions.Period
+	}
+
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(opts.Digits))
+	query.Set("period", fmt.Sprint(int(opts.Period/time.Second)))
+
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + accountName,
+		RawQuery: query.Encode(),
+	}
+	return u.String()
+}
+
+func decodeTOTPSecret(secret string) ([]byte, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TOTP secret: %w", err)
+	}
+	return key, nil
+}
+
+// totpCode implements the HOTP truncation of RFC 4226 section 5.3.
+func totpCode(key []byte, counter uint64, digits int) string {
+	var message [8]byte
+	binary.BigEndian.PutUint64(message[:], counter)
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(message[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	modulus := uint32(1)
+	for i := 0; i < digits; i++ {
+		modulus *= 10
+	}
+
+	return fmt.Sprintf("%0*d", digits, value%modulus)
+}
+
+// generateRecoveryCodes returns n random recovery codes formatted as xxxxx-xxxxx.
+func generateRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, n)
+	for i := range codes {
+		raw := make([]byte, 7)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, fmt.Errorf("unable to generate recovery code: %w", err)
+		}
+		encoded := strings.ToLower(totpEncoding.EncodeToString(raw))[:10]
+		codes[i] = encoded[:5] + "-" + encoded[5:]
+	}
+	return codes, nil
+}
+
+// normalizeRecoveryCode lower-cases a recovery code and keeps only its letters and digits, so
+// that codes typed in capitals, without the hyphen or with other separators still match.
+func normalizeRecoveryCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		}
+		return -1
+	}, code)
+}
+
+// BeginTOTPEnrollmentInput represents the input data for starting TOTP enrollment.
+type BeginTOTPEnrollmentInput struct {
+	UserID uuid.UUID
+	// Issuer is shown by authenticator apps next to the account name.
+	Issuer string
+	// AccountName is usually the user's email address.
+	AccountName string
+	// QRCodeSize is the width and height of the QR code in pixels. Defaults to 256.
+	QRCodeSize int
+}
+
+// TOTPEnrollment is a pending TOTP enrollment. It takes effect once ConfirmTOTPEnrollment
+// is called with a code generated from Secret.
+type TOTPEnrollment struct {
+	Secret    string `json:"secret"`
+	URI       string `json:"uri"`
+	QRCodePNG []byte `json:"qr_code_png"`
+}
+
+// BeginTOTPEnrollment provisions a new TOTP secret for the user on the server and returns it
+// with an otpauth:// URI and a QR code for authenticator apps. Two factor authentication is
+// not enabled until the enrollment is confirmed.
+func (c *Client) BeginTOTPEnrollment(ctx context.Context, input BeginTOTPEnrollmentInput) (*TOTPEnrollment, error) {
+	// Validate the input
+	if input.UserID == uuid.Nil {
+		return nil, errors.New("user ID is required")
+	}
+	if input.Issuer == "" || input.AccountName == "" {
+		return nil, errors.New("issuer and account name are required")
+	}
+	if strings.Contains(input.Issuer, ":") {
+		return nil, errors.New("issuer must not contain a colon")
+	}
+	if input.QRCodeSize == 0 {
+		input.QRCodeSize = 256
+	}
+
+	// Ask the server for a pending secret
+	var response struct {
+		Secret string `json:"secret"`
+	}
+	if err := c.doJSON(ctx, http.MethodPost, []string{"users", input.UserID.String(), "totp", "enrollment"}, nil, http.StatusCreated, &response, nil); err != nil {
+		return nil, err
+	}
+	if _, err := decodeTOTPSecret(response.Secret); err != nil {
+		return nil, fmt.Errorf("server returned an unusable secret: %w", err)
+	}
+
+	uri := TOTPKeyURI(input.Issuer, input.AccountName, response.Secret, DefaultTOTPOptions)
+	png, err := qrcode.Encode(uri, qrcode.Medium, input.QRCodeSize)
+	if err != nil {
+		return nil, fmt.Errorf("unable to render QR code: %w", err)
+	}
+
+	return &TOTPEnrollment{
+		Secret:    response.Secret,
+		URI:       uri,
+		QRCodePNG: png,
+	}, nil
+}
+
+// ConfirmTOTPEnrollment completes a pending enrollment with a code from the user's
+// authenticator app and turns on two factor authentication for the user.
+func (c *Client) ConfirmTOTPEnrollment(ctx context.Context, userID uuid.UUID, code string) error {
+	if userID == uuid.Nil {
+		return errors.New("user ID is required")
+	}
+	if code == "" {
+		return errors.New("code is required")
+	}
+
+	payload := map[string]string{"code": strings.TrimSpace(code)}
+	return c.doJSON(ctx, http.MethodPost, []string{"users", userID.String(), "totp", "enrollment", "confirm"}, payload, http.StatusOK, nil, nil)
+}
+
+// VerifyTOTP checks a code against the user's confirmed TOTP secret. Codes from
+// DefaultTOTPOptions.Skew periods either side of the server's clock are accepted.
+func (c *Client) VerifyTOTP(ctx context.Context, userID uuid.UUID, code string) (bool, error) {
+	if userID == uuid.Nil {
+		return false, errors.New("user ID is required")
+	}
+	if code == "" {
+		return false, errors.New("code is required")
+	}
+
+	payload := struct {
+		Code string `json:"code"`
+		Skew uint   `json:"skew"`
+	}{
+		Code: strings.TrimSpace(code),
+		Skew: DefaultTOTPOptions.Skew,
+	}
+
+	var response struct {
+		Valid bool `json:"valid"`
+	}
+	if err := c.doJSON(ctx, http.MethodPost, []string{"users", userID.String(), "totp", "verify"}, payload, http.StatusOK, &response, nil); err != nil {
+		return false, err
+	}
+
+	return response.Valid, nil
+}
+
+// GenerateRecoveryCodes issues a new set of recovery codes for the user, replacing any
+// previous set. Only SHA-256 digests are sent to the server, so the returned codes
+// cannot be retrieved again.
+func (c *Client) GenerateRecoveryCodes(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	if userID == uuid.Nil {
+		return nil, errors.New("user ID is required")
+	}
+
+	codes, err := generateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = hashSecret(normalizeRecoveryCode(code))
+	}
+
+	payload := map[string][]string{"code_hashes": hashes}
+	if err := c.doJSON(ctx, http.MethodPost, []string{"users", userID.String(), "totp", "recovery-codes"}, payload, http.StatusCreated, nil, nil); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// RedeemRecoveryCode uses up one of the user's recovery codes in place of a TOTP code and
+// returns the number of codes left.
+func (c *Client) RedeemRecoveryCode(ctx context.Context, userID uuid.UUID, code string) (int, error) {
+	if userID == uuid.Nil {
+		return 0, errors.New("user ID is required")
+	}
+	if code == "" {
+		return 0, errors.New("recovery code is required")
+	}
+
+	payload := map[string]string{"code_hash": hashSecret(normalizeRecoveryCode(code))}
+
+	var response struct {
+		Remaining int `json:"remaining"`
+	}
+	if err := c.doJSON(ctx, http.MethodPost, []string{"users", userID.String(), "totp", "recovery-codes", "redeem"}, payload, http.StatusOK, &response, nil); err != nil {
+		return 0, err
+	}
+
+	return response.Remaining, nil
+}
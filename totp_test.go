@@ -0,0 +1,86 @@
+package accountslib
+
+import (
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 test key of RFC 6238 appendix B, base32 encoded.
+var rfc6238Secret = totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestGenerateTOTPCodeRFC6238(t *testing.T) {
+	// The SHA-1 test vectors of RFC 6238 appendix B
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+
+	for _, tt := range tests {
+		code, err := GenerateTOTPCode(rfc6238Secret, time.Unix(tt.unix, 0), TOTPOptions{Digits: 8})
+		if err != nil {
+			t.Fatalf("GenerateTOTPCode(%d) returned error: %v", tt.unix, err)
+		}
+		if code != tt.code {
+			t.Errorf("GenerateTOTPCode(%d) = %s, want %s", tt.unix, code, tt.code)
+		}
+	}
+}
+
+func TestValidateTOTPCode(t *testing.T) {
+	at := time.Unix(1111111111, 0)
+
+	tests := []struct {
+		name string
+		code string
+		t    time.Time
+		skew uint
+		want bool
+	}{
+		{name: "current period", code: "14050471", t: at, want: true},
+		{name: "surrounding spaces", code: " 14050471 ", t: at, want: true},
+		{name: "previous period within skew", code: "14050471", t: at.Add(30 * time.Second), skew: 1, want: true},
+		{name: "previous period without skew", code: "14050471", t: at.Add(30 * time.Second)},
+		{name: "outside skew", code: "14050471", t: at.Add(90 * time.Second), skew: 1},
+		{name: "wrong code", code: "14050472", t: at, skew: 1},
+		{name: "wrong length", code: "4050471", t: at, skew: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			valid, err := ValidateTOTPCode(rfc6238Secret, tt.code, tt.t, TOTPOptions{Digits: 8, Skew: tt.skew})
+			if err != nil {
+				t.Fatalf("ValidateTOTPCode returned error: %v", err)
+			}
+			if valid != tt.want {
+				t.Errorf("ValidateTOTPCode = %v, want %v", valid, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeRecoveryCode(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"abcde-fghij", "abcdefghij"},
+		{"ABCDE-FGHIJ", "abcdefghij"},
+		{" abcde fghij\n", "abcdefghij"},
+		{"abcde_fghij", "abcdefghij"},
+		{"abcdefghij", "abcdefghij"},
+		{"ab2de–fg7ij", "ab2defg7ij"},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeRecoveryCode(tt.code); got != tt.want {
+			t.Errorf("normalizeRecoveryCode(%q) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
@@ -11,7 +11,6 @@ import (
 	"net/http"
 	"net/url"
 	"path"
-	"regexp"
 	"time"
 
 	validation "github.com/go-ozzo/ozzo-validation"
@@ -55,6 +54,7 @@ type UpdateUserPayload struct {
 	FirstName        *string `json:"first_name,omitempty"`
 	LastName         *string `json:"last_name,omitempty"`
 	DisplayName      *string `json:"display_name,omitempty"`
+	PhoneNumber      *string `json:"phone_number,omitempty"`
 	IsActive         *bool   `json:"is_active,omitempty"`
 	IsEmailVerified  *bool   `json:"is_email_verified,omitempty"`
 	IsPhoneVerified  *bool   `json:"is_phone_verified,omitempty"`
@@ -113,13 +113,11 @@ type UserInRoleCheckData struct {
 	RoleID uuid.UUID `json:"role_id"`
 }
 
-var emailRegex = regexp.MustCompile(`^[a-z0-9._%+\-]+@[a-z0-9.\-]+\.[a-z]{2,4}$`)
-
-// Validate validates the UserRegistrationData fields.
+// Validate validates the UserRegistrationData fields. The password is checked by the client's
+// PasswordPolicy in RegisterUser, so that its length limits are the only ones that apply.
 func (d *UserRegistrationData) Validate() error {
 	return validation.ValidateStruct(d,
-		validation.Field(&d.Email, validation.Required, validation.Length(3, 255), validation.Match(emailRegex).Error("Invalid email")),
-		validation.Field(&d.Password, validation.Required, validation.Length(8, 255)),
+		validation.Field(&d.Email, validation.Required, validation.Length(3, 255), emailRule),
 	)
 }
 
@@ -130,8 +128,25 @@ func (c *Client) RegisterUser(data *UserRegistrationData) error {
 		return err
 	}
 
+	// Normalize the email address
+	registration := *data
+	registration.Email, err = c.emailPolicy().Normalize(data.Email)
+	if err != nil {
+		return err
+	}
+
+	// Apply the password policy
+	if err := c.passwordPolicy().Validate(registration.Password, PasswordContext{Email: registration.Email}); err != nil {
+		return err
+	}
+
+	// Refuse a second account under an equivalent address
+	if err := c.checkCanonicalDuplicate(registration.Email, uuid.Nil); err != nil {
+		return err
+	}
+
 	// Marshal the input data
-	jsonData, err := json.Marshal(data)
+	jsonData, err := json.Marshal(registration)
 	if err != nil {
 		return err
 	}
@@ -167,7 +182,7 @@ func (c *Client) RegisterUser(data *UserRegistrationData) error {
 func (u *User) Validate() error {
 	return validation.ValidateStruct(u,
 		validation.Field(&u.ID, validation.Required),
-		validation.Field(&u.Email, validation.Required, validation.Match(emailRegex).Error("Invalid email")),
+		validation.Field(&u.Email, validation.Required, emailRule),
 		// validation.Field(&u.Username, validation.Required),
 		// ... add validation for other fields as needed
 	)
@@ -180,8 +195,18 @@ func (c *Client) CreateUser(data *User) error {
 		return err
 	}
 
+	// Normalize the email address and apply the email policy
+	user := *data
+	user.Email, err = c.emailPolicy().Normalize(data.Email)
+	if err != nil {
+		return err
+	}
+	if err := c.checkCanonicalDuplicate(user.Email, data.ID); err != nil {
+		return err
+	}
+
 	// Marshal the input data
-	jsonData, err := json.Marshal(data)
+	jsonData, err := json.Marshal(user)
 	if err != nil {
 		return err
 	}
@@ -249,11 +274,14 @@ func (c *Client) GetUserByID(id uuid.UUID) (*User, error) {
 	return &user, nil
 }
 
+// GetUserByEmail fetches a user by email address. The address is looked up in its NormalizeEmail
+// form, so the local part is lowercased: a user stored with a mixed-case address before
+// normalization was introduced is only found if the server compares addresses case-insensitively.
 func (c *Client) GetUserByEmail(email string) (*User, error) {
-	// Validate email
-	emailRegex := regexp.MustCompile(`^[a-z0-9._%+\-]+@[a-z0-9.\-]+\.[a-z]{2,4}$`)
-	if !emailRegex.MatchString(email) {
-		return nil, errors.New("invalid email")
+	// Normalize the email address
+	email, err := NormalizeEmail(email)
+	if err != nil {
+		return nil, err
 	}
 
 	// Create the URL for the request
@@ -261,7 +289,8 @@ func (c *Client) GetUserByEmail(email string) (*User, error) {
 	if err != nil {
 		return nil, fmt.Errorf("unable to parse base URL: %w", err)
 	}
-	u.Path = path.Join(u.Path, "api", "users", email)
+	// The local part may contain "/", "?" or "#", so the address is escaped as a single segment
+	u = u.JoinPath("api", "users", url.PathEscape(email))
 
 	// Create a new HTTP request
 	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
@@ -297,6 +326,52 @@ func (c *Client) GetUserByEmail(email string) (*User, error) {
 	return &user, nil
 }
 
+// getUserByCanonicalEmail returns the user whose address has the given CanonicalizeEmail form,
+// or nil when there is none.
+func (c *Client) getUserByCanonicalEmail(canonical string) (*User, error) {
+	// Create the URL for the request
+	u, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse base URL: %w", err)
+	}
+	u = u.JoinPath("api", "users", "canonical-email", url.PathEscape(canonical))
+
+	// Create a new HTTP request
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create new request: %w", err)
+	}
+
+	// Set the appropriate headers
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	req.Header.Set("X-API-Key", c.ApiKey)
+
+	// Send the HTTP request
+	res, err := c.HttpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to send request: %w", err)
+	}
+	defer res.Body.Close()
+
+	// Check the status code
+	if res.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(res.Body)
+		return nil, fmt.Errorf("unexpected status code: got %v, body: %s", res.StatusCode, body)
+	}
+
+	// Unmarshal the response into a User struct
+	var user User
+	if err := json.NewDecoder(res.Body).Decode(&user); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &user, nil
+}
+
 func (d *CheckPasswordHashData) Validate() error {
 	return validation.ValidateStruct(d,
 		validation.Field(&d.UserID, validation.Required),
@@ -344,9 +419,50 @@ func (c *Client) CheckPasswordHash(data *CheckPasswordHashData) (bool, error) {
 	return true, nil
 }
 
+// ChangePasswordInput represents the input data for a user changing their own password.
+type ChangePasswordInput struct {
+	UserID          uuid.UUID
+	CurrentPassword string
+	NewPassword     string
+}
+
+// ChangePassword replaces a user's password after checking the current one and applying the password policy.
+// Like CompletePasswordReset it then signs the user out everywhere, so the caller must start a new session.
+func (c *Client) ChangePassword(ctx context.Context, input ChangePasswordInput) error {
+	// Validate the input
+	if input.UserID == uuid.Nil {
+		return errors.New("user ID is required")
+	}
+	if input.CurrentPassword == "" {
+		return errors.New("current password is required")
+	}
+
+	// Check the current password
+	ok, err := c.CheckPasswordHash(&CheckPasswordHashData{UserID: input.UserID, Password: input.CurrentPassword})
+	if err != nil || !ok {
+		return errors.New("current password is incorrect")
+	}
+
+	// Apply the password policy
+	user, err := c.GetUserByID(input.UserID)
+	if err != nil {
+		return fmt.Errorf("unable to fetch user: %w", err)
+	}
+	if err := c.passwordPolicy().Validate(input.NewPassword, PasswordContext{Email: user.Email, Username: user.Username}); err != nil {
+		return err
+	}
+
+	if err := c.setUserPassword(ctx, input.UserID, input.NewPassword); err != nil {
+		return err
+	}
+
+	return c.signOutEverywhere(ctx, input.UserID)
+}
+
 func (u *UpdateUserPayload) Validate() error {
 	return validation.ValidateStruct(u,
-		validation.Field(&u.Email, validation.NilOrNotEmpty, is.Email),
+		validation.Field(&u.Email, validation.NilOrNotEmpty, emailRule),
+		validation.Field(&u.PhoneNumber, validation.NilOrNotEmpty, validation.Match(e164Regex).Error("must be an E.164 phone number")),
 		// add more field validations here as per your requirement
 	)
 }
@@ -357,8 +473,21 @@ func (c *Client) UpdateUser(userID uuid.UUID, payload *UpdateUserPayload) error
 		return err
 	}
 
+	// Normalize the email address and apply the email policy
+	update := *payload
+	if payload.Email != nil {
+		email, err := c.emailPolicy().Normalize(*payload.Email)
+		if err != nil {
+			return err
+		}
+		if err := c.checkCanonicalDuplicate(email, userID); err != nil {
+			return err
+		}
+		update.Email = &email
+	}
+
 	// Marshal the payload
-	jsonPayload, err := json.Marshal(payload)
+	jsonPayload, err := json.Marshal(update)
 	if err != nil {
 		return err
 	}
@@ -482,7 +611,7 @@ func (c *Client) SetUserActiveStatus(event *SetUserActiveStatusEvent) error {
 func (e *VerifyEmailEvent) Validate() error {
 	return validation.ValidateStruct(e,
 		validation.Field(&e.UserID, validation.Required),
-		validation.Field(&e.Email, validation.Required, validation.Match(emailRegex).Error("Invalid email")),
+		validation.Field(&e.Email, validation.Required, emailRule),
 	)
 }
 
@@ -525,6 +654,9 @@ func (c *Client) VerifyEmail(event *VerifyEmailEvent) error {
 	return nil
 }
 
+// VerifyPhoneNumber marks a user's phone number as verified without checking it.
+//
+// Deprecated: Use SendPhoneVerificationCode and ConfirmPhoneVerification, which prove the user can receive texts at the number.
 func (c *Client) VerifyPhoneNumber(user *User) error {
 	// Check if phone number exists
 	if user.PhoneNumber == "" {
@@ -718,6 +850,11 @@ func (c *Client) AddRoleToUser(userID, roleID uuid.UUID) error {
 		return errors.New("invalid input: user ID and role ID are required")
 	}
 
+	// Check the role may be given to the user
+	if err := c.enforceCompanyDomainByRoleID(userID, roleID, "", uuid.Nil); err != nil {
+		return err
+	}
+
 	// Create the endpoint URL
 	endpointURL, err := url.Parse(c.BaseURL)
 	if err != nil {
@@ -857,6 +994,11 @@ func (c *Client) AssignRoleToUser(data *AssignRoleData) error {
 		return err
 	}
 
+	// Check the role may be given to the user
+	if err := c.enforceCompanyDomainByRoleID(data.UserID, data.RoleID, "", uuid.Nil); err != nil {
+		return err
+	}
+
 	// Marshal the data
 	jsonData, err := json.Marshal(data)
 	if err != nil {